@@ -2,6 +2,9 @@ package main
 
 import (
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"tg_bot/configs"
 	"tg_bot/internal/bot"
@@ -13,11 +16,20 @@ func main() {
 		log.Fatalf("Error loading config: %v", err)
 	}
 
-	b, err := bot.New(cfg.TelegramToken, cfg.DeepgramToken)
+	b, err := bot.New(cfg)
 	if err != nil {
 		log.Fatalf("Error creating bot: %v", err)
 	}
 
+	// По Ctrl+C или SIGTERM бот отвечает на уже полученные сообщения и закрывает логи
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-stop
+		log.Printf("Received %v, stopping", sig)
+		b.Stop()
+	}()
+
 	if err := b.Run(); err != nil {
 		log.Fatalf("Error running bot: %v", err)
 	}
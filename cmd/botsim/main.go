@@ -0,0 +1,219 @@
+// Command botsim is a terminal chat with the bot for local development: no
+// Telegram token or phone is needed. Typed lines go through the same update
+// handler as in Run, replies and their inline buttons are printed, and buttons
+// are pressed by number. Voice messages are read from local .ogg files; without
+// a Deepgram key the simulator asks what was said instead of recognizing it.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"tg_bot/configs"
+	"tg_bot/internal/bot"
+	"tg_bot/internal/bot/bottest"
+)
+
+const usage = `Пиши сообщения как в Telegram, команды тоже: /start, /help.
+  #2                   нажать кнопку 2
+  :voice путь.ogg      отправить голосовое
+  :sticker 😴          отправить стикер
+  :state               состояние диалога
+  :wait 31m            промотать время
+  :help                эта подсказка
+  :quit                выйти (или Ctrl+D)`
+
+// button is a numbered button of the printed replies
+type button struct {
+	messageID int
+	data      string
+}
+
+// sim is one chat with the bot
+type sim struct {
+	bot    *bot.Bot
+	server *bottest.Server
+	chatID int64
+	out    io.Writer
+	now    time.Time
+	// shown is how many messages of the chat are printed
+	shown   int
+	buttons []button
+	voices  int
+}
+
+func main() {
+	lexiconDir := flag.String("lexicon", "", "directory with mood lexicon files (default from the config)")
+	logDir := flag.String("logs", "", "directory for the bot logs (default a temporary one, so the simulated chat does not end up in logs/feedback.jsonl)")
+	chatID := flag.Int64("chat", 1, "chat ID of the simulated user")
+	verbose := flag.Bool("v", false, "show the bot log")
+	flag.Parse()
+
+	// Без .env тоже работаем: токен Telegram не нужен, а без ключа Deepgram речь подменяется
+	cfg, err := configs.LoadConfig()
+	if err != nil {
+		cfg = &configs.Config{LexiconDir: "configs/lexicon", DeepgramToken: os.Getenv("DEEPGRAM_TOKEN")}
+	}
+	if *lexiconDir != "" {
+		cfg.LexiconDir = *lexiconDir
+	}
+	cfg.LogDir = *logDir
+	if cfg.LogDir == "" {
+		dir, err := os.MkdirTemp("", "botsim")
+		if err != nil {
+			log.Fatalf("Error creating log directory: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		cfg.LogDir = dir
+	}
+	// Каждая строка получает ответ сразу, без ожидания следующей
+	cfg.DebounceInterval = 0
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	// Бот ходит в поддельный Bot API по HTTP, так голосовые скачиваются как настоящие
+	server := bottest.NewServer("")
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	api, err := bot.NewAPI("botsim", httpServer.URL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to fake Bot API: %v\n", err)
+		os.Exit(1)
+	}
+	b, err := bot.NewWithAPI(api, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating bot: %v\n", err)
+		os.Exit(1)
+	}
+	defer b.Close()
+
+	in := bufio.NewScanner(os.Stdin)
+	s := &sim{bot: b, server: server, chatID: *chatID, out: os.Stdout, now: time.Now()}
+	b.SetClock(func() time.Time { return s.now })
+	if cfg.DeepgramToken == "" {
+		b.SetTranscriber(promptTranscriber{in: in, out: os.Stdout})
+		fmt.Println("Ключа Deepgram нет: для голосовых бот спросит, что в них сказано.")
+	}
+	fmt.Println(usage)
+
+	for {
+		fmt.Print("> ")
+		if !in.Scan() {
+			fmt.Println()
+			return
+		}
+		line := strings.TrimSpace(in.Text())
+		if line == ":quit" {
+			return
+		}
+		if err := s.handle(line); err != nil {
+			fmt.Println("! " + err.Error())
+		}
+		s.printReplies()
+	}
+}
+
+// handle sends one typed line to the bot
+func (s *sim) handle(line string) error {
+	command, arg, _ := strings.Cut(line, " ")
+	switch {
+	case line == "":
+		return nil
+	case line == ":help":
+		fmt.Fprintln(s.out, usage)
+	case line == ":state":
+		fmt.Fprintln(s.out, "= "+string(s.bot.State(s.chatID)))
+	case command == ":wait":
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return err
+		}
+		s.now = s.now.Add(d)
+		fmt.Fprintf(s.out, "= %s спустя %v\n", s.bot.State(s.chatID), d)
+	case command == ":sticker":
+		s.bot.HandleUpdate(bottest.Sticker(s.chatID, arg))
+	case command == ":voice":
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return err
+		}
+		s.voices++
+		fileID := fmt.Sprintf("voice%d", s.voices)
+		s.server.AddFile(fileID, "voice/"+fileID+".oga", data)
+		s.bot.HandleUpdate(bottest.Voice(s.chatID, fileID))
+	case strings.HasPrefix(line, "#"):
+		n, err := strconv.Atoi(strings.TrimPrefix(line, "#"))
+		if err != nil || n < 1 || n > len(s.buttons) {
+			return fmt.Errorf("нет кнопки %s", line)
+		}
+		pressed := s.buttons[n-1]
+		s.bot.HandleUpdate(bottest.Press(s.chatID, pressed.messageID, pressed.data))
+	case strings.HasPrefix(line, ":"):
+		return fmt.Errorf("неизвестная команда %s, см. :help", command)
+	default:
+		s.bot.HandleUpdate(bottest.TextMessage(s.chatID, line))
+	}
+	return nil
+}
+
+// printReplies prints the new messages of the bot and numbers their buttons.
+// Кнопки старых ответов остаются доступны, пока новые ответы приходят без кнопок.
+func (s *sim) printReplies() {
+	messages := s.server.Messages(s.chatID)
+	var buttons []button
+	for _, m := range messages[s.shown:] {
+		fmt.Fprintln(s.out, "🤖 "+indent(m.Text))
+		for _, row := range m.Keyboard {
+			var labels []string
+			for _, b := range row {
+				buttons = append(buttons, button{messageID: m.ID, data: *b.CallbackData})
+				labels = append(labels, fmt.Sprintf("[%d] %s", len(buttons), b.Text))
+			}
+			fmt.Fprintln(s.out, "   "+strings.Join(labels, "  "))
+		}
+	}
+	s.shown = len(messages)
+	if len(buttons) > 0 {
+		s.buttons = buttons
+	}
+}
+
+// promptTranscriber stands in for Deepgram: it asks the developer what the
+// voice message says
+type promptTranscriber struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+func (t promptTranscriber) Transcribe(oggPath string) (string, error) {
+	fmt.Fprint(t.out, "🎤 что сказано в голосовом? ")
+	if !t.in.Scan() {
+		return "", fmt.Errorf("no transcript: %v", t.in.Err())
+	}
+	text := strings.TrimSpace(t.in.Text())
+	if text == "" {
+		return "", fmt.Errorf("no transcript")
+	}
+	return text, nil
+}
+
+// indent shifts the continuation lines of a reply under its first line
+func indent(text string) string {
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = "   " + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,314 @@
+// Command botspec checks the bot against its behavior spec: scripted
+// conversations from design/mood_responses.spec are played through the real
+// update handler with a fake Telegram API, and every reply, keyboard and
+// dialog state is compared with the script.
+//
+// Script lines:
+//
+//	## name          a new scenario in a new chat
+//	> text           the user sends a message; consecutive lines arrive in quick succession
+//	> sticker: 😴    the user sends a sticker with the emoji
+//	> /command args  the user sends a command
+//	* label          the user presses the newest button with the label
+//	< text           the bot sends the text; a trailing "..." matches a prefix
+//	[a] [b]          a row of buttons of the message above; "[]" means no buttons
+//	= state          the dialog state of the chat
+//	~ 61m            time passes
+//
+// Every message the bot sends must be listed. Lines starting with "#" are comments.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"tg_bot/configs"
+	"tg_bot/internal/bot"
+	"tg_bot/internal/bot/bottest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// line is one line of a script with its position for error messages
+type line struct {
+	n    int
+	text string
+}
+
+type scenario struct {
+	name  string
+	lines []line
+}
+
+func main() {
+	specPath := flag.String("spec", "design/mood_responses.spec", "script with the expected conversations")
+	lexiconDir := flag.String("lexicon", "configs/lexicon", "directory with mood lexicon files")
+	run := flag.String("run", "", "run only the scenarios whose name contains this text")
+	verbose := flag.Bool("v", false, "show the bot log")
+	flag.Parse()
+
+	scenarios, err := readSpec(*specPath)
+	if err != nil {
+		log.Fatalf("Error reading spec: %v", err)
+	}
+
+	logDir, err := os.MkdirTemp("", "botspec")
+	if err != nil {
+		log.Fatalf("Error creating log directory: %v", err)
+	}
+	defer os.RemoveAll(logDir)
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+	b, api, now, err := newBot(*lexiconDir, logDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating bot: %v\n", err)
+		os.Exit(1)
+	}
+	defer b.Close()
+
+	var passed, failed int
+	for i, sc := range scenarios {
+		if *run != "" && !strings.Contains(sc.name, *run) {
+			continue
+		}
+		p := &player{bot: b, api: api, chatID: int64(i + 1), now: now}
+		if err := p.play(sc); err != nil {
+			failed++
+			fmt.Printf("FAIL %s\n     %v\n", sc.name, err)
+			continue
+		}
+		passed++
+		fmt.Printf("ok   %s\n", sc.name)
+	}
+
+	fmt.Printf("\n%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// newBot creates the bot the scenarios are played against: a fake Telegram API
+// and a clock that only the "~" lines move
+func newBot(lexiconDir, logDir string) (*bot.Bot, *bottest.API, *time.Time, error) {
+	api := bottest.NewAPI()
+	// Таймер склейки сообщений не сработает сам: сценарий отправляет пачку через FlushPending
+	b, err := bot.NewWithAPI(api, &configs.Config{LexiconDir: lexiconDir, LogDir: logDir, DebounceInterval: time.Hour})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	b.SetClock(func() time.Time { return now })
+	return b, api, &now, nil
+}
+
+// readSpec splits the script into scenarios
+func readSpec(path string) ([]scenario, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var scenarios []scenario
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(text, "## "):
+			scenarios = append(scenarios, scenario{name: strings.TrimPrefix(text, "## ")})
+		case text == "" || strings.HasPrefix(text, "#"):
+			continue
+		case len(scenarios) == 0:
+			return nil, fmt.Errorf("%s:%d: line outside of a scenario", path, n)
+		default:
+			last := &scenarios[len(scenarios)-1]
+			last.lines = append(last.lines, line{n: n, text: text})
+		}
+	}
+	return scenarios, scanner.Err()
+}
+
+// player plays one scenario in its own chat
+type player struct {
+	bot    *bot.Bot
+	api    *bottest.API
+	chatID int64
+	now    *time.Time
+	// seen is how many messages of the chat are already checked
+	seen int
+	// last is the message checked by the latest "<" line
+	last *bottest.Message
+	// checkedRows is how many button rows of last the script has compared
+	checkedRows int
+}
+
+func (p *player) play(sc scenario) error {
+	for _, l := range sc.lines {
+		if err := p.step(l.text); err != nil {
+			return fmt.Errorf("line %d: %s: %v", l.n, l.text, err)
+		}
+	}
+	p.bot.FlushPending()
+	if err := p.checkRowsDone(); err != nil {
+		return err
+	}
+	return p.checkAllSeen()
+}
+
+func (p *player) step(text string) error {
+	kind, arg, _ := strings.Cut(text, " ")
+	if !strings.HasPrefix(kind, ">") {
+		// Все, что идет после сообщений пользователя, видит уже ответ на них
+		p.bot.FlushPending()
+	}
+
+	switch kind {
+	case ">":
+		p.bot.HandleUpdate(p.message(arg))
+	case "*":
+		if err := p.checkAllSeen(); err != nil {
+			return err
+		}
+		m, b, ok := p.api.FindButton(p.chatID, arg)
+		if !ok {
+			return fmt.Errorf("no button %q on screen", arg)
+		}
+		p.bot.HandleUpdate(bottest.Press(p.chatID, m.ID, *b.CallbackData))
+	case "<":
+		return p.expectMessage(arg)
+	case "=":
+		if got := string(p.bot.State(p.chatID)); got != arg {
+			return fmt.Errorf("state is %s", got)
+		}
+	case "~":
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return err
+		}
+		*p.now = p.now.Add(d)
+	default:
+		if strings.HasPrefix(text, "[") {
+			return p.expectRow(text)
+		}
+		return fmt.Errorf("unknown line")
+	}
+	return nil
+}
+
+// message builds an incoming message from a "> " line
+func (p *player) message(text string) tgbotapi.Update {
+	if emoji, ok := strings.CutPrefix(text, "sticker: "); ok {
+		return bottest.Sticker(p.chatID, emoji)
+	}
+	return bottest.TextMessage(p.chatID, text)
+}
+
+// expectMessage checks the next message the bot sent to the chat
+func (p *player) expectMessage(want string) error {
+	if err := p.checkRowsDone(); err != nil {
+		return err
+	}
+	m := p.nextMessage()
+	if m == nil {
+		return fmt.Errorf("the bot sent nothing")
+	}
+	p.last = m
+	p.checkedRows = 0
+	if prefix, ok := strings.CutSuffix(want, "..."); ok {
+		if !strings.HasPrefix(m.Text, prefix) {
+			return fmt.Errorf("got %q", m.Text)
+		}
+		return nil
+	}
+	if m.Text != want {
+		return fmt.Errorf("got %q", m.Text)
+	}
+	return nil
+}
+
+// expectRow checks the next row of buttons of the message checked last
+func (p *player) expectRow(text string) error {
+	if p.last == nil {
+		return fmt.Errorf("buttons without a message")
+	}
+	var want []string
+	if text != "[]" {
+		for _, label := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(text, "["), "]"), "] [") {
+			want = append(want, label)
+		}
+	}
+
+	rows := p.last.Keyboard
+	i := p.checkedRows
+	p.checkedRows++
+	if len(want) == 0 {
+		if len(rows) > 0 {
+			return fmt.Errorf("got buttons %s", formatRows(rows))
+		}
+		return nil
+	}
+	if i >= len(rows) || !sameLabels(rows[i], want) {
+		return fmt.Errorf("got buttons %s", formatRows(rows))
+	}
+	return nil
+}
+
+// checkRowsDone fails if the script lists only some of the button rows of the
+// message checked last. Кнопки проверяются, только если сценарий их перечисляет.
+func (p *player) checkRowsDone() error {
+	if p.last == nil || p.checkedRows == 0 || p.checkedRows >= len(p.last.Keyboard) {
+		return nil
+	}
+	return fmt.Errorf("%q has more buttons: %s", p.last.Text, formatRows(p.last.Keyboard[p.checkedRows:]))
+}
+
+// nextMessage returns the next unchecked message of the chat
+func (p *player) nextMessage() *bottest.Message {
+	messages := p.api.ChatMessages(p.chatID)
+	if p.seen >= len(messages) {
+		return nil
+	}
+	p.seen++
+	return &messages[p.seen-1]
+}
+
+// checkAllSeen fails if the bot sent messages the scenario does not list
+func (p *player) checkAllSeen() error {
+	if m := p.nextMessage(); m != nil {
+		return fmt.Errorf("unexpected message %q %s", m.Text, formatRows(m.Keyboard))
+	}
+	return nil
+}
+
+func sameLabels(row []tgbotapi.InlineKeyboardButton, labels []string) bool {
+	if len(row) != len(labels) {
+		return false
+	}
+	for i, b := range row {
+		if b.Text != labels[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func formatRows(rows [][]tgbotapi.InlineKeyboardButton) string {
+	var out []string
+	for _, row := range rows {
+		var labels []string
+		for _, b := range row {
+			labels = append(labels, "["+b.Text+"]")
+		}
+		out = append(out, strings.Join(labels, " "))
+	}
+	return strings.Join(out, " / ")
+}
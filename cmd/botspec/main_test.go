@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+// TestSpec plays design/mood_responses.spec, so go test ./... checks the bot
+// against its behavior spec too
+func TestSpec(t *testing.T) {
+	scenarios, err := readSpec("../../design/mood_responses.spec")
+	if err != nil {
+		t.Fatalf("Error reading spec: %v", err)
+	}
+	b, api, now, err := newBot("../../configs/lexicon", t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating bot: %v", err)
+	}
+	defer b.Close()
+
+	for i, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			p := &player{bot: b, api: api, chatID: int64(i + 1), now: now}
+			if err := p.play(sc); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+// Command fakebotapi serves a fake Telegram Bot API (bottest.Server) for
+// running cmd/bot end to end without network. Point the bot at it with
+// TELEGRAM_API_URL and script the conversation through the /_test/ endpoints.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"tg_bot/internal/bot/bottest"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8081", "address to listen on")
+	token := flag.String("token", "", "the only accepted bot token; empty accepts any")
+	flag.Parse()
+
+	log.Printf("Fake Bot API listening on http://%s", *addr)
+	if err := http.ListenAndServe(*addr, bottest.NewServer(*token)); err != nil {
+		log.Fatalf("Error serving fake Bot API: %v", err)
+	}
+}
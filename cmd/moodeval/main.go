@@ -0,0 +1,214 @@
+// Command moodeval measures mood classification on a labeled corpus: per-class
+// precision, recall and F1, a confusion matrix and the misclassified examples.
+// With -baseline it works as a regression gate and exits with status 1 when
+// accuracy drops below the checked-in value.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"tg_bot/internal/mood"
+)
+
+// baseline is the accuracy recorded for a golden corpus
+type baseline struct {
+	Corpus   string  `json:"corpus"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+// misclassified is an example the classifier got wrong
+type misclassified struct {
+	example mood.Example
+	result  mood.Result
+}
+
+func main() {
+	corpus := flag.String("corpus", "internal/mood/testdata/golden.jsonl", "comma-separated labeled corpora, JSONL or CSV")
+	lexiconDir := flag.String("lexicon", "configs/lexicon", "directory with mood lexicon files")
+	modelPath := flag.String("model", "", "evaluate a Naive Bayes model from cmd/moodtrain instead of the lexicon")
+	baselinePath := flag.String("baseline", "", "JSON file with the accepted accuracy; fail if the corpus scores lower")
+	update := flag.Bool("update-baseline", false, "write the current accuracy to -baseline instead of checking it")
+	flag.Parse()
+
+	classifier, err := loadClassifier(*modelPath, *lexiconDir)
+	if err != nil {
+		log.Fatalf("Error loading classifier: %v", err)
+	}
+
+	var examples []mood.Example
+	for _, path := range strings.Split(*corpus, ",") {
+		loaded, err := mood.LoadExamples(strings.TrimSpace(path))
+		if err != nil {
+			log.Fatalf("Error loading corpus: %v", err)
+		}
+		examples = append(examples, loaded...)
+	}
+	if len(examples) == 0 {
+		log.Fatalf("Corpus %s is empty", *corpus)
+	}
+
+	// confusion[want][got]
+	confusion := make(map[string]map[string]int)
+	var wrong []misclassified
+	correct := 0
+	for _, e := range examples {
+		result := classifier.Analyze(e.Text)
+		if confusion[e.Mood] == nil {
+			confusion[e.Mood] = make(map[string]int)
+		}
+		confusion[e.Mood][result.Mood]++
+		if result.Mood == e.Mood {
+			correct++
+		} else {
+			wrong = append(wrong, misclassified{example: e, result: result})
+		}
+	}
+	accuracy := float64(correct) / float64(len(examples))
+
+	classes := classList(confusion)
+	fmt.Printf("Classifier: %s\nCorpus: %s, %d examples\n\n", classifier.Name(), *corpus, len(examples))
+	printMetrics(classes, confusion)
+	fmt.Println()
+	printConfusion(classes, confusion)
+	fmt.Println()
+	printMisclassified(wrong)
+	fmt.Printf("\nAccuracy: %.1f%% (%d/%d)\n", 100*accuracy, correct, len(examples))
+
+	if *baselinePath == "" {
+		return
+	}
+	if *update {
+		if err := writeBaseline(*baselinePath, baseline{Corpus: *corpus, Accuracy: accuracy}); err != nil {
+			log.Fatalf("Error writing baseline: %v", err)
+		}
+		fmt.Printf("Baseline %s updated\n", *baselinePath)
+		return
+	}
+
+	want, err := readBaseline(*baselinePath)
+	if err != nil {
+		log.Fatalf("Error reading baseline: %v", err)
+	}
+	// Сравниваем с запасом на погрешность округления при записи базовой линии
+	if accuracy+1e-9 < want.Accuracy {
+		fmt.Printf("FAIL: accuracy %.1f%% is below the baseline %.1f%%\n", 100*accuracy, 100*want.Accuracy)
+		os.Exit(1)
+	}
+	fmt.Printf("OK: baseline %.1f%%\n", 100*want.Accuracy)
+}
+
+func loadClassifier(modelPath, lexiconDir string) (mood.Classifier, error) {
+	if modelPath != "" {
+		return mood.LoadBayes(modelPath)
+	}
+	return mood.NewAnalyzer(lexiconDir)
+}
+
+// classList orders the labels and predictions like mood.Categories, neutral last
+func classList(confusion map[string]map[string]int) []string {
+	seen := make(map[string]bool)
+	for want, row := range confusion {
+		seen[want] = true
+		for got := range row {
+			seen[got] = true
+		}
+	}
+
+	var classes []string
+	for _, c := range append(slices.Clone(mood.Categories), mood.Neutral) {
+		if seen[c] {
+			classes = append(classes, c)
+			delete(seen, c)
+		}
+	}
+	// Метки, которых нет среди категорий (опечатки в корпусе), тоже показываем
+	var unknown []string
+	for c := range seen {
+		unknown = append(unknown, c)
+	}
+	slices.Sort(unknown)
+	return append(classes, unknown...)
+}
+
+func printMetrics(classes []string, confusion map[string]map[string]int) {
+	fmt.Printf("%-10s %9s %9s %9s %8s\n", "class", "precision", "recall", "f1", "support")
+	for _, c := range classes {
+		var tp, predicted, support int
+		tp = confusion[c][c]
+		for _, want := range classes {
+			predicted += confusion[want][c]
+		}
+		for _, n := range confusion[c] {
+			support += n
+		}
+
+		precision := ratio(tp, predicted)
+		recall := ratio(tp, support)
+		var f1 float64
+		if precision+recall > 0 {
+			f1 = 2 * precision * recall / (precision + recall)
+		}
+		fmt.Printf("%-10s %9.2f %9.2f %9.2f %8d\n", c, precision, recall, f1, support)
+	}
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+func printConfusion(classes []string, confusion map[string]map[string]int) {
+	fmt.Println("Confusion matrix (rows: expected, columns: predicted)")
+	fmt.Printf("%-10s", "")
+	for _, c := range classes {
+		fmt.Printf(" %9s", c)
+	}
+	fmt.Println()
+	for _, want := range classes {
+		fmt.Printf("%-10s", want)
+		for _, got := range classes {
+			fmt.Printf(" %9d", confusion[want][got])
+		}
+		fmt.Println()
+	}
+}
+
+func printMisclassified(wrong []misclassified) {
+	fmt.Printf("Misclassified: %d\n", len(wrong))
+	for _, w := range wrong {
+		var matches []string
+		for _, m := range w.result.Matches {
+			matches = append(matches, fmt.Sprintf("%s→%s %.2g", m.Token, m.Category, m.Weight))
+		}
+		fmt.Printf("  %q: expected %s, got %s (confidence %.2f) [%s]\n",
+			w.example.Text, w.example.Mood, w.result.Mood, w.result.Confidence, strings.Join(matches, ", "))
+	}
+}
+
+func readBaseline(path string) (baseline, error) {
+	var b baseline
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return b, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return b, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return b, nil
+}
+
+func writeBaseline(path string, b baseline) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal baseline: %v", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,137 @@
+// Command moodtrain trains the Naive Bayes mood model offline from labeled
+// examples and the bot log, and saves it for the bot to load with MOOD_MODEL
+// or SHADOW_MODEL.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"tg_bot/internal/logger"
+	"tg_bot/internal/mood"
+)
+
+func main() {
+	logs := flag.String("logs", "logs/bot.log", "comma-separated bot logs to bootstrap labels from, empty to skip")
+	data := flag.String("data", "", "comma-separated labeled corpora: JSONL with {\"text\", \"mood\"} or CSV with text,mood columns")
+	out := flag.String("out", "models/mood_bayes.json", "where to save the model")
+	alpha := flag.Float64("alpha", 1, "additive smoothing of word counts")
+	flag.Parse()
+
+	var examples []mood.Example
+	for _, path := range splitList(*logs) {
+		fromLog, err := readLog(path)
+		if err != nil {
+			log.Fatalf("Error reading log: %v", err)
+		}
+		log.Printf("Read %d labeled messages from %s", len(fromLog), path)
+		examples = append(examples, fromLog...)
+	}
+	for _, path := range splitList(*data) {
+		labeled, err := mood.LoadExamples(path)
+		if err != nil {
+			log.Fatalf("Error reading examples: %v", err)
+		}
+		log.Printf("Read %d examples from %s", len(labeled), path)
+		examples = append(examples, labeled...)
+	}
+
+	model, err := mood.TrainBayes(examples, *alpha)
+	if err != nil {
+		log.Fatalf("Error training model: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(*out), 0755); err != nil {
+		log.Fatalf("Error creating model directory: %v", err)
+	}
+	if err := model.Save(*out); err != nil {
+		log.Fatalf("Error saving model: %v", err)
+	}
+
+	// Точность на обучающих данных показывает только, что модель выучила корпус;
+	// настоящую оценку дает moodeval на отложенных примерах
+	correct := 0
+	for _, e := range examples {
+		if model.Analyze(e.Text).Mood == e.Mood {
+			correct++
+		}
+	}
+
+	classes := make([]string, 0, len(model.Docs))
+	for c := range model.Docs {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+	for _, c := range classes {
+		fmt.Printf("%-10s %6d examples\n", c, model.Docs[c])
+	}
+	fmt.Printf("%d words, training accuracy %.1f%%, saved to %s\n",
+		len(model.Words), 100*float64(correct)/float64(len(examples)), *out)
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// readLog takes the user messages that got a mood from the bot log. The labels come
+// from the classifier that was running then, so the model starts as its copy and
+// improves as corrected examples are added with -data.
+func readLog(path string) ([]mood.Example, error) {
+	var examples []mood.Example
+	err := scanLines(path, func(line []byte) error {
+		var entry logger.LogEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		if entry.MessageType == "callback" || entry.Mood == "" || entry.Content == "" {
+			return nil
+		}
+		// Смешанное настроение не годится как одна метка
+		if len(entry.Moods) > 1 {
+			return nil
+		}
+		label := entry.Mood
+		if label == "neutral_final" {
+			label = mood.Neutral
+		}
+		// Метка относится ко всему проанализированному тексту, вместе с предыдущими ответами
+		text := strings.Join(append(entry.Context, entry.Content), ". ")
+		examples = append(examples, mood.Example{Text: text, Mood: label})
+		return nil
+	})
+	return examples, err
+}
+
+// scanLines calls parse for every non-empty line of a JSONL file
+func scanLines(path string, parse func(line []byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		if err := parse(line); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+	}
+	return scanner.Err()
+}
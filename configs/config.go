@@ -1,18 +1,37 @@
 package configs
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	TelegramToken string
-	DeepgramToken string
-	IsDev         bool
+	// Bot API server, e.g. a local one or cmd/fakebotapi; empty means api.telegram.org
+	TelegramAPIURL string
+	DeepgramToken  string
+	IsDev          bool
+	// Directory with the mood lexicon JSON files
+	LexiconDir string
+	// Directory with a candidate lexicon that runs in shadow mode; empty disables it
+	ShadowLexiconDir string
+	// Naive Bayes model trained by cmd/moodtrain, used instead of the lexicon when set
+	MoodModel string
+	// Naive Bayes model that runs in shadow mode
+	ShadowModel string
+	// Directory for bot.log, feedback.jsonl and shadow.log
+	LogDir string
+	// Messages sent within this interval are merged and answered once; zero disables it
+	DebounceInterval time.Duration
 }
 
+// defaultDebounceInterval is long enough to type a short follow-up message
+const defaultDebounceInterval = 2 * time.Second
+
 func LoadConfig() (*Config, error) {
 	envFile := ".env"
 	if os.Getenv("ENV") == "dev" {
@@ -25,9 +44,36 @@ func LoadConfig() (*Config, error) {
 
 	isDev, _ := strconv.ParseBool(os.Getenv("DEV"))
 
+	lexiconDir := os.Getenv("LEXICON_DIR")
+	if lexiconDir == "" {
+		lexiconDir = "configs/lexicon"
+	}
+
+	logDir := os.Getenv("LOG_DIR")
+	if logDir == "" {
+		logDir = "logs"
+	}
+
+	debounceInterval := defaultDebounceInterval
+	if value := os.Getenv("DEBOUNCE_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DEBOUNCE_INTERVAL %q: %v", value, err)
+		}
+		debounceInterval = interval
+	}
+
 	return &Config{
-		TelegramToken: os.Getenv("TELEGRAM_TOKEN"),
-		DeepgramToken: os.Getenv("DEEPGRAM_TOKEN"),
-		IsDev:         isDev,
+		TelegramToken:  os.Getenv("TELEGRAM_TOKEN"),
+		TelegramAPIURL: os.Getenv("TELEGRAM_API_URL"),
+		DeepgramToken:  os.Getenv("DEEPGRAM_TOKEN"),
+		IsDev:          isDev,
+		LexiconDir:     lexiconDir,
+		// Кандидат только логируется и не влияет на ответы
+		ShadowLexiconDir: os.Getenv("SHADOW_LEXICON_DIR"),
+		MoodModel:        os.Getenv("MOOD_MODEL"),
+		ShadowModel:      os.Getenv("SHADOW_MODEL"),
+		LogDir:           logDir,
+		DebounceInterval: debounceInterval,
 	}, nil
 }
@@ -0,0 +1,442 @@
+# Исполняемая спецификация к design/mood_responses.md: go run ./cmd/botspec или go test ./cmd/botspec
+#
+# Каждый сценарий — отдельный чат. "> " — сообщение пользователя, подряд идущие
+# сообщения склеиваются; "< " — ответ бота ("..." в конце — начало ответа);
+# строки в скобках — ряды кнопок ответа выше, "[]" — ответ без кнопок;
+# "* " — нажатие кнопки; "= " — состояние диалога; "~ " — прошедшее время.
+# Все сообщения бота должны быть перечислены.
+#
+# Голосовые сообщения здесь не проверяются: для них нужен Deepgram. После
+# распознавания они идут тем же путем, что и текст.
+
+## 1. Позитивное настроение
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> мне хорошо
+< Рад слышать, что у тебя всё хорошо! 😊 Давай сохраним это настроение! Предлагаю сделать практику осознанности.
+[Вижу, слышу, чувствую]
+[ABC noting]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+[👍 Да, верно]
+[Устал] [Грустно] [Хорошо] [Бодро]
+= idle
+* Вижу, слышу, чувствую
+< Практика 'Вижу, слышу, чувствую' 🌟...
+= idle
+
+## 2. Энергичное настроение
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> полон энергии
+< Отлично! 💪 Такая энергия - это здорово! Держи этот настрой и используй его для достижения своих целей!
+[]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= idle
+
+## 3. Легкая усталость
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> немного устал
+< Похоже, ты немного подустал. Вот пара коротких упражнений, чтобы взбодриться.
+[Упражнение 1] [Упражнение 4]
+[Пропустить]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= waiting_for_exercise
+
+## 3. Обычная усталость
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> устал
+< Сожалею, что ты сейчас устал. Давай я предложу тебе 4 упражнения, которые помогут восстановиться.
+[Упражнение 1] [Упражнение 2]
+[Упражнение 3] [Упражнение 4]
+[Пропустить]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= waiting_for_exercise
+
+## 3. Сильная усталость
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> капец как устал
+< Похоже, ты совсем вымотан. 😔 Давай я предложу тебе 4 упражнения, которые помогут восстановиться, а потом постарайся как следует отдохнуть.
+[Упражнение 1] [Упражнение 2]
+[Упражнение 3] [Упражнение 4]
+[Пропустить]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= waiting_for_exercise
+* Упражнение 2
+< Упражнение 2: Растяжка шеи...
+= idle
+
+## 3. Стикер
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> sticker: 😴
+< Сожалею, что ты сейчас устал. Давай я предложу тебе 4 упражнения, которые помогут восстановиться.
+[Упражнение 1] [Упражнение 2]
+[Упражнение 3] [Упражнение 4]
+[Пропустить]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= waiting_for_exercise
+
+## 4. Негативное настроение
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> мне хреново
+< Мне жаль, что тебе сейчас нелегко. Давай я предложу тебе несколько упражнений, которые помогут улучшить настроение.
+[Упражнение 1] [Упражнение 2]
+[Упражнение 3] [Упражнение 4]
+[Пропустить]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= waiting_for_exercise
+
+## 4.1. Тревога
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> мне тревожно
+< Похоже, тебе тревожно. Давай попробуем заземлиться — это помогает вернуться в настоящий момент. Выбери упражнение.
+[Упражнение 5] [Упражнение 1]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= idle
+
+## 4.1. Злость
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> я злюсь
+< Понимаю, тебя что-то сильно задело. 😤 Давай сначала выдохнем и немного остынем — вот упражнения.
+[Упражнение 6] [Упражнение 3]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= idle
+
+## 4.1. Грусть
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> мне грустно
+< Мне жаль, что тебе грустно. 💙 Давай попробуем немного позаботиться о себе — вот что может помочь.
+[Упражнение 8] [Упражнение 3]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= idle
+
+## 4.1. Грусть стикером
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> sticker: 😭
+< Мне жаль, что тебе грустно...
+< Я правильно понял твое настроение?...
+= idle
+
+## 4.1. Стресс
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> я в стрессе
+< Похоже, на тебя сейчас много всего навалилось. Давай на пару минут снимем напряжение — выбери упражнение.
+[Упражнение 7] [Упражнение 1]
+[Упражнение 2]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= idle
+
+## 4.1. Спокойствие
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> мне спокойно
+< Здорово, что тебе спокойно. 🍃 Давай закрепим это состояние — вот практики осознанности.
+[Вижу, слышу, чувствую]
+[ABC noting]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= idle
+
+## 4.1. Уточненное настроение важнее родительского
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> хреново и тревожно
+< Похоже, тебе тревожно. Давай попробуем заземлиться — это помогает вернуться в настоящий момент. Выбери упражнение.
+[Упражнение 5] [Упражнение 1]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+
+## 5. Три непонятных ответа
+> привет
+< Привет! 👋
+< Как ты сейчас?
+= waiting_for_mood
+> ну такое
+< Расскажи мне побольше.
+[]
+= waiting_for_mood
+> сижу дома
+< Расскажи мне побольше.
+> обычный день
+< Спасибо за ответ! Надеюсь, у тебя будет хороший день! 🌞
+[]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= idle
+
+## 5. Ответы анализируются вместе
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> не устал
+< Расскажи мне побольше.
+> и не сонный
+< Отлично! 💪 Такая энергия - это здорово! Держи этот настрой и используй его для достижения своих целей!
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= idle
+
+## 6. Смешанное настроение
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> устал, но доволен
+< Слышу, что ты устал, но при этом у тебя хорошее настроение. Давай поддержим и то, и другое — вот упражнения на выбор.
+[Упражнение 1] [Упражнение 2]
+[Упражнение 3] [Упражнение 4]
+[Вижу, слышу, чувствую]
+[ABC noting]
+[Пропустить]
+< Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.
+= waiting_for_exercise
+
+## 6. Похожие состояния не противопоставляются
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> радостно на душе
+< Слышу, что в тебе много энергии и у тебя хорошее настроение...
+< Я правильно понял твое настроение?...
+= idle
+
+## 6. Два тяжелых состояния
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> устал и грустно
+< Слышу, что ты устал и тебе грустно...
+< Я правильно понял твое настроение?...
+= waiting_for_exercise
+
+## 7. Приветствие с опечаткой и в транслите
+> приивет
+< Привет! 👋
+< Как ты сейчас?
+= waiting_for_mood
+> /stop
+< Хорошо, закончим на этом. Напиши /start или «привет», когда захочешь поговорить. 👋
+= idle
+> privet
+< Привет! 👋
+< Как ты сейчас?
+= waiting_for_mood
+
+## 7. Тайм-аут ожидания ответа
+> привет
+< Привет! 👋
+< Как ты сейчас?
+~ 31m
+= idle
+
+## 7.1. /start в любом состоянии
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> устал
+< Сожалею, что ты сейчас устал...
+< Я правильно понял твое настроение?...
+= waiting_for_exercise
+> /start
+< Привет! 👋
+< Как ты сейчас?
+= waiting_for_mood
+
+## 7.1. /start с deep link
+> /start exercises
+< Вот все упражнения и практики: ...
+[Упражнение 1] [Упражнение 2] [Упражнение 3] [Упражнение 4]
+[Упражнение 5] [Упражнение 6] [Упражнение 7] [Упражнение 8]
+[Вижу, слышу, чувствую] [ABC noting]
+[Пропустить]
+= waiting_for_exercise
+
+## 7.1. /mood
+> /mood
+< Как ты сейчас?
+= waiting_for_mood
+> /mood мне грустно
+< Мне жаль, что тебе грустно...
+< Я правильно понял твое настроение?...
+= idle
+
+## 7.1. /help и неизвестная команда
+> /help
+< Я помогаю разобраться с настроением и предлагаю упражнения...
+> /foo
+< Не знаю такой команды. Вот что я умею:...
+
+## 7.1. Текст перед командой обрабатывается до нее
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> устал
+> /stop
+< Сожалею, что ты сейчас устал...
+< Я правильно понял твое настроение?...
+< Хорошо, закончим на этом...
+= idle
+
+## 8. Подтверждение настроения
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> мне грустно
+< Мне жаль, что тебе грустно...
+< Я правильно понял твое настроение?...
+* 👍 Да, верно
+< Спасибо! 🙏
+= idle
+
+## 8. Исправление настроения
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> мне хорошо
+< Рад слышать...
+< Я правильно понял твое настроение?...
+* Устал
+< Понял, спасибо, что поправил! Сожалею, что ты сейчас устал. Давай я предложу тебе 4 упражнения, которые помогут восстановиться.
+[Упражнение 1] [Упражнение 2]
+[Упражнение 3] [Упражнение 4]
+[Пропустить]
+= waiting_for_exercise
+
+## 8.1. Выбор упражнения текстом
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> устал
+< Сожалею, что ты сейчас устал...
+< Я правильно понял твое настроение?...
+> давай 3
+< Упражнение 3: Мини-прогулка...
+[]
+= idle
+
+## 8.1. Выбор упражнения по названию
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> устал
+< Сожалею, что ты сейчас устал...
+< Я правильно понял твое настроение?...
+> дыхание
+< Упражнение 1: Глубокое дыхание...
+= idle
+
+## 8.1. Отказ кнопкой
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> мне хреново
+< Мне жаль, что тебе сейчас нелегко...
+< Я правильно понял твое настроение?...
+* Пропустить
+< Хорошо, в другой раз. 🙂 Если захочешь поговорить, просто напиши мне.
+= idle
+
+## 8.1. Отказ текстом
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> устал
+< Сожалею, что ты сейчас устал...
+< Я правильно понял твое настроение?...
+> не сейчас
+< Хорошо, в другой раз. 🙂 Если захочешь поговорить, просто напиши мне.
+= idle
+
+## 8.1. Непонятный ответ
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> устал
+< Сожалею, что ты сейчас устал...
+< Я правильно понял твое настроение?...
+> а что это
+< Выбери упражнение кнопкой или напиши его номер или название, например «1» или «дыхание». Если не хочется, напиши «пропустить».
+= waiting_for_exercise
+
+## 8.1. Привет начинает заново
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> устал
+< Сожалею, что ты сейчас устал...
+< Я правильно понял твое настроение?...
+> привет
+< Привет! 👋
+< Как ты сейчас?
+= waiting_for_mood
+
+## 8.1. Тайм-аут выбора
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> устал
+< Сожалею, что ты сейчас устал...
+< Я правильно понял твое настроение?...
+~ 59m
+= waiting_for_exercise
+~ 2m
+= idle
+
+## Сообщения подряд склеиваются
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> я сегодня
+> что-то
+> совсем вымотался
+< Похоже, ты совсем вымотан...
+< Я правильно понял твое настроение?...
+= waiting_for_exercise
+
+## Приветствие в склеенных сообщениях отвечается отдельно
+> привет
+> я очень устал
+< Привет! 👋
+< Как ты сейчас?
+< Похоже, ты совсем вымотан...
+< Я правильно понял твое настроение?...
+= waiting_for_exercise
+
+## Без приветствия настроение не разбирается
+> устал
+= idle
+> /stop
+< Хорошо, закончим на этом...
+> мне грустно
+= idle
+
+## 8. Ответ на старый вопрос после приветствия не сохраняется
+> привет
+< Привет! 👋
+< Как ты сейчас?
+> мне грустно
+< Мне жаль, что тебе грустно...
+< Я правильно понял твое настроение?...
+> привет
+< Привет! 👋
+< Как ты сейчас?
+* Устал
+= waiting_for_mood
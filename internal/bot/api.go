@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// API is the part of the Telegram Bot API the bot uses. *tgbotapi.BotAPI
+// implements it; bottest.API is an in-memory fake for exercising the handlers
+// without network.
+type API interface {
+	// Send sends a message and returns it as Telegram saw it
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	// Request makes a call that does not send a message: callback answers,
+	// keyboard edits, the command menu
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+	// GetFileDirectURL returns the download link of a file, e.g. a voice message
+	GetFileDirectURL(fileID string) (string, error)
+	// GetUpdatesChan starts receiving updates
+	GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel
+	// StopReceivingUpdates stops polling for updates; Bot.Stop calls it
+	StopReceivingUpdates()
+}
+
+// NewAPI connects to the Bot API server at serverURL, e.g. a local
+// telegram-bot-api or bottest.Server, or to api.telegram.org if it is empty
+func NewAPI(token, serverURL string) (API, error) {
+	if serverURL == "" {
+		api, err := tgbotapi.NewBotAPI(token)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Authorized on account %s", api.Self.UserName)
+		return api, nil
+	}
+
+	serverURL = strings.TrimSuffix(serverURL, "/")
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint(token, serverURL+"/bot%s/%s")
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Authorized on account %s at %s", api.Self.UserName, serverURL)
+	return serverAPI{BotAPI: api, fileEndpoint: serverURL + "/file/bot%s/%s"}, nil
+}
+
+// serverAPI talks to a Bot API server other than api.telegram.org. tgbotapi
+// always builds file links to api.telegram.org, so they are built here.
+type serverAPI struct {
+	*tgbotapi.BotAPI
+	fileEndpoint string
+}
+
+func (a serverAPI) GetFileDirectURL(fileID string) (string, error) {
+	file, err := a.GetFile(tgbotapi.FileConfig{FileID: fileID})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(a.fileEndpoint, a.Token, file.FilePath), nil
+}
@@ -2,656 +2,604 @@ package bot
 
 import (
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
+	"tg_bot/configs"
+	"tg_bot/internal/dialog"
 	"tg_bot/internal/logger"
+	"tg_bot/internal/mood"
 	"tg_bot/internal/speech"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type Bot struct {
-	api *tgbotapi.BotAPI
-	// Map to store conversation states
-	conversationStates map[int64]string
-	// Speech recognition client
-	speechClient *speech.DeepgramClient
+	api API
+	// Conversation state of every chat
+	dialog *dialog.Machine
+	// Speech recognition of voice messages
+	transcriber speech.Transcriber
 	// Map to store mood recognition attempts
 	moodAttempts map[int64]int
+	// Recent answers of every chat, classified together while the mood is unclear
+	moodContext map[int64][]string
 	// Logger
 	logger *logger.Logger
+	// Mood classifier that picks the replies
+	classifier mood.Classifier
+	// Candidate classifier in shadow mode: its results are only compared and logged
+	shadow mood.Classifier
+	// Log of disagreements between the classifier and the shadow one
+	shadowLogger *logger.Logger
+	// Last classified message of every chat, waiting for the user to confirm the mood
+	pendingFeedback map[int64]pendingFeedback
+	// Labeled examples from mood confirmations and corrections
+	feedbackLogger *logger.Logger
+	// Messages of every chat waiting to be answered together, see debounce
+	batches map[int64]*messageBatch
+	// How long to wait for the next message before answering; zero answers at once
+	debounceInterval time.Duration
+	// Chats whose batch is ready, sent by the debounce timers
+	flushes chan int64
+	// Closed by Close, so timers that fire after Run has returned do not block
+	done chan struct{}
+	// Closed by Stop to end Run without waiting for the current long poll
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
-func New(telegramToken, deepgramToken string) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(telegramToken)
+// lexiconPollInterval is how often the lexicon files are checked for changes
+const lexiconPollInterval = 5 * time.Second
+
+func New(cfg *configs.Config) (*Bot, error) {
+	api, err := NewAPI(cfg.TelegramToken, cfg.TelegramAPIURL)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithAPI(api, cfg)
+}
+
+// NewWithAPI creates the bot on top of a ready Telegram API: a client pointed
+// at a fake server by tgbotapi.NewBotAPIWithClient or the in-memory bottest.API
+func NewWithAPI(api API, cfg *configs.Config) (*Bot, error) {
+	// Загружаем и проверяем словари настроений (или обученную модель) до запуска бота
+	classifier, err := loadClassifier(cfg.MoodModel, cfg.LexiconDir)
 	if err != nil {
 		return nil, err
 	}
+	log.Printf("Mood classifier: %s", classifier.Name())
 
 	// Инициализируем логгер
-	logger, err := logger.New("logs/bot.log")
+	botLogger, err := logger.New(filepath.Join(cfg.LogDir, "bot.log"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize logger: %v", err)
 	}
 
-	return &Bot{
-		api:                api,
-		conversationStates: make(map[int64]string),
-		speechClient:       speech.NewDeepgramClient(deepgramToken),
-		moodAttempts:       make(map[int64]int),
-		logger:             logger,
-	}, nil
-}
+	// Подтверждения и исправления настроения — размеченные примеры для обучения и оценки
+	feedbackLogger, err := logger.New(filepath.Join(cfg.LogDir, "feedback.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize feedback logger: %v", err)
+	}
 
-// analyzeMood analyzes the text and returns the detected mood
-func analyzeMood(text string) string {
-	text = strings.ToLower(text)
-
-	// Positive mood indicators
-	positiveWords := []string{
-		// Базовые позитивные состояния
-		"радостн", "весел", "счастлив", "хорош", "отличн", "прекрасн", "замечательн", "классн", "супер", "крут",
-		// Эмоциональные реакции
-		"кайф", "охуенн", "заеб", "пиздат", "шикарн", "бомб", "огн", "вау", "ухты", "здоров",
-		// Усилители и сравнения
-		"лучш", "потрясающ", "восхитительн", "изумительн", "невероятн", "фантастическ", "чудесн", "волшебн",
-		// Базовые эмоции
-		"радостн", "спокойн", "тепл", "умиротворен", "благодарн", "доволен", "счастлив", "весел", "позитивн",
-		// Глубокие состояния
-		"вдохновен", "окрылен", "одухотворен", "просветлен", "гармоничн", "целостн", "наполнен", "богат",
-		// Физические ощущения
-		"легк", "свеж", "бодр", "энергичн", "сильн", "здоров", "жив", "активн",
-		// Действия и состояния
-		"улыбаюсь", "смеюсь", "пою", "танцую", "творю", "создаю", "развиваюсь", "расту",
-
-		// Базовые положительные состояния
-		"кайф", "охуенн", "заеб", "пиздат", "огонь", "ахуенн", "волшебн", "балдеж", "душевн", "чум", "кайфец", "кайфушк", "сладк", "красот", "тепл", "милот", "лампов", "трепетн", "пушечн", "праздник",
-
-		// Эмоциональные реакции
-		"раду", "мурашк", "приятн", "трогательн", "крут", "слез", "красив", "классн", "спокойн", "глубин", "прослез", "щем", "счаст", "любл", "обожа", "сердечк", "зашл", "тема",
-
-		// Усилители и сравнения
-		"как", "будто", "словно", "точно", "прям", "уж", "вот", "ну", "аж", "через", "край", "слож", "надо",
-
-		// Базовые эмоции
-		"радостн", "спокойн", "легк", "приятн", "тепл", "уютн", "светл", "хорош", "мягк", "вдохновл", "трогательн", "умиротворен", "благодарн", "довольн", "счаст", "восхищен", "нежн", "любов", "уверен", "забот", "интерес", "любопытн",
-
-		// Глубокие состояния
-		"полнот", "смысл", "волнен", "принят", "наслажден", "восторг", "удовлетворен", "гармони", "ясн", "открыт", "довер", "легк", "поко", "надежд", "искрен", "целост", "благ", "благополуч", "признательн", "очарован",
-
-		// Физические ощущения
-		"тепл", "свет", "обня", "сердц", "поет", "внутр", "место",
-
-		// Действия и состояния
-		"улыба", "получил", "чувству", "дума", "тронул", "произошл", "доволен", "довольн",
-
-		// Существующие слова
-		"радостно", "весело", "прекрасно", "замечательно", "чудесно",
-		"восхитительно", "потрясающе", "изумительно", "великолепно",
-		"блестяще", "превосходно", "идеально", "совершенно",
-		"прекрасный день", "замечательный день", "чудесный день",
-		"в восторге", "в восхищении", "в эйфории",
-		"на седьмом небе", "на вершине счастья", "полон радости",
-		"полна радости", "счастливый", "счастливая",
-		"доволен", "довольна", "удовлетворен", "удовлетворена",
-		"в хорошем настроении", "в отличном настроении",
-		"в прекрасном настроении", "в чудесном настроении",
-		"в восхитительном настроении", "в потрясающем настроении",
-		"хорошо", "хорошая", "хороший", "хорошее",
-	}
-
-	// Negative mood indicators
-	negativeWords := []string{
-		// Базовые негативные состояния
-		"тосклив", "тревожн", "пуст", "обидн", "тяжел", "больн", "одинок", "горьк", "несправедлив", "страшн", "неловк", "стыдн", "злост", "безысходн", "уныл", "мучительн", "раздража", "разочарован", "нудн", "мерзк", "мерзост", "отвращен", "тревог", "скук", "апати", "ненавиж", "отчаян", "беспомощн",
-
-		// Матерные и разговорные выражения
-		"хуев", "паршив", "дерьмов", "говен", "бес", "жоп", "пизд", "еба", "надоел", "чертик", "ад", "бляд", "хренов", "хуйн", "сран", "сук", "хуяр", "херн", "черт", "жоп", "больн", "херов", "нахуй", "надежд", "выт", "скреб", "сдох", "заеб",
-
-		// Эмоциональные состояния
-		"понима", "раздража", "не так", "успоко", "плака", "застря", "дело", "лишн", "невыносим", "хоч", "испорт", "отпуст", "почему", "валит", "смысл", "дыр", "сер", "раду", "говор",
-
-		// Отрицания и усилители
-		"не", "ни", "вс", "как", "будто", "словно", "точно", "опят", "внутр", "ничего", "никому", "ни с кем",
-
-		// Существующие слова
-		"грустно", "печально", "тоскливо", "мрачно", "уныло",
-		"депрессивно", "подавленно", "разбито", "разбита",
-		"опустошен", "опустошена", "разочарован", "разочарована",
-		"в отчаянии", "в унынии", "в депрессии",
-		"в плохом настроении", "в ужасном настроении",
-		"в отвратительном настроении", "в мерзком настроении",
-		"в паршивом настроении", "в скверном настроении",
-		"в дурном настроении", "в гадком настроении",
-		"в мерзопакостном настроении", "в отвратном настроении",
-		"в ужасном состоянии", "в плохом состоянии",
-		"в отвратительном состоянии", "в мерзком состоянии",
-		"в паршивом состоянии", "в скверном состоянии",
-		"в дурном состоянии", "в гадком состоянии",
-		"в мерзопакостном состоянии", "в отвратном состоянии",
-	}
-
-	// Tired state indicators
-	tiredWords := []string{
-		// Базовые состояния
-		"устал", "устал", "вымотан", "выжат", "опустошен", "изможден", "разбит", "истощен", "перегруз", "перегор", "сонн", "мутн", "напряжен", "предел",
-
-		// Физические ощущения
-		"ватн", "голов", "тяжел", "шум", "плыв", "туп", "засыпа", "перегрев", "замедл", "туман", "тело", "диван", "леж", "стен", "поезд", "навалил", "тян", "одеял",
-
-		// Ментальные состояния
-		"сообража", "вар", "мозг", "ресурс", "сил", "автопилот", "зомб", "провал", "существу", "ком", "тряпк", "говн", "лошад", "паш",
-
-		// Эмоциональные состояния
-		"выгоран", "нетерпим", "эмоциональн", "нахуй", "заеб", "задолб", "вымота", "еба", "пиздец", "сдох", "бляд", "охует", "говн", "говор",
-
-		// Отрицания и усилители
-		"не", "нет", "никак", "больш", "последн", "вс", "просто", "как", "будто", "хоть", "уже", "больш", "всё", "все", "ничего", "ничего", "никакой", "никакая",
-
-		// Действия и состояния
-		"лечь", "лежать", "исчез", "выспат", "кончит", "встават", "полз", "встава", "тян", "вар", "плыв", "провалива", "лез", "работа", "выжра", "высос", "заеба", "задолб", "вымота", "еба", "сдох", "говн", "говор",
-
-		// Сравнения
-		"как", "будто", "словно", "точно", "похож", "напомина", "подобн", "такой", "такая", "такое", "такие",
-
-		// Существующие слова
-		"устал", "устала", "утомлен", "утомлена",
-		"нет сил", "нет энергии", "упадок сил",
-		"хочу спать", "сонный", "сонная",
-		"вымотан", "вымотана", "измотан", "измотана",
-		"нет настроения", "усталость", "утомление",
-		"хочу отдохнуть", "нужен отдых", "нужен сон",
-		"изнурен", "изнурена", "истощен", "истощена",
-		"нет бодрости", "вялый", "вялая",
-	}
-
-	// Energized state indicators
-	energizedWords := []string{
-		// Ментальная бодрость
-		"ясн", "собран", "сконцентрирован", "сфокусирован", "внимательн", "включен", "волн", "соображаю", "поток", "четк", "структурн", "остр", "голов", "гибк", "мышлен",
-		"мозг", "работает", "соображаю", "раз", "два", "решаю", "налету", "мысл", "ясн", "полочк", "проснул", "порядок",
-		"схватываю", "лету", "башк", "варит", "голов", "тормозит", "врубаюсь", "полуслов", "соображаю", "мозг", "тупит", "фигачу", "шерлок",
-
-		// Физическая бодрость
-		"бодр", "легк", "свеж", "заряжен", "жив", "подвижн", "гибк", "пружин", "энерг", "прет", "ход", "активн", "летиш", "теле", "огонь",
-		"легкост", "теле", "крыл", "выросл", "могу", "заряд", "полн", "двигаться", "усидеть", "тело", "радуется",
-		"пр", "прет", "огурчик", "бегаю", "заведен", "хрен", "догониш", "ног", "несут", "ебашу", "спортзал", "качаю", "энерг", "охуенно", "теле", "пляшет", "бодрячком", "остановить", "заткнеш",
-
-		// Эмоциональный подъём
-		"ресурс", "вдохновлен", "стабильн", "радостн", "наполнен", "поток", "уверенн", "спокойн", "баланс", "душ", "цельн", "интерес",
-		"делиться", "плечу", "хорошо", "нравится", "жив", "возможн", "вдохновляюсь", "процесс",
-		"заебись", "кайфую", "жизн", "охуенно", "душ", "ебать", "прет", "добро", "летиш", "улыбаеш", "балдежн", "состояние", "хуярю", "удовольстви", "идет", "надо", "жизн", "огонь", "аплодирую", "светится", "позитив",
-
-		// Существующие слова
-		"энергичн", "бодр", "бодра", "полон сил", "полна сил",
-		"готов", "готова", "все могу", "все смогу",
-		"отличное настроение", "прекрасное настроение",
-		"полон энергии", "полна энергии", "много энергии",
-		"активен", "активна", "бодрость", "энергия",
-		"готов к работе", "готова к работе",
-		"все по плечу", "все под силу",
-		"отличное самочувствие", "прекрасное самочувствие",
-		"полон энтузиазма", "полна энтузиазма",
-	}
-
-	// Check for energized words first
-	for _, word := range energizedWords {
-		if strings.Contains(text, word) {
-			return "energized"
-		}
+	dialogs, err := dialog.New(dialog.Transitions, dialog.Timeouts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build dialog: %v", err)
 	}
 
-	// Then check for tired words
-	for _, word := range tiredWords {
-		if strings.Contains(text, word) {
-			return "tired"
-		}
+	b := &Bot{
+		api:              api,
+		dialog:           dialogs,
+		transcriber:      speech.NewDeepgramClient(cfg.DeepgramToken),
+		moodAttempts:     make(map[int64]int),
+		moodContext:      make(map[int64][]string),
+		logger:           botLogger,
+		classifier:       classifier,
+		pendingFeedback:  make(map[int64]pendingFeedback),
+		feedbackLogger:   feedbackLogger,
+		batches:          make(map[int64]*messageBatch),
+		debounceInterval: cfg.DebounceInterval,
+		flushes:          make(chan int64),
+		done:             make(chan struct{}),
+		stop:             make(chan struct{}),
 	}
 
-	// Then check for positive words
-	for _, word := range positiveWords {
-		if strings.Contains(text, word) {
-			return "positive"
+	// Попытки и история ответов нужны только пока бот выясняет настроение
+	b.dialog.OnEnter(dialog.Idle, b.resetMood)
+	b.dialog.OnEnter(dialog.WaitingForExercise, b.resetMood)
+
+	// Кандидат в теневом режиме: отвечает по-прежнему основной классификатор
+	if cfg.ShadowModel != "" && cfg.ShadowLexiconDir != "" {
+		return nil, fmt.Errorf("only one shadow classifier is supported, got both SHADOW_MODEL and SHADOW_LEXICON_DIR")
+	}
+	if cfg.ShadowModel != "" || cfg.ShadowLexiconDir != "" {
+		shadow, err := loadClassifier(cfg.ShadowModel, cfg.ShadowLexiconDir)
+		if err != nil {
+			return nil, fmt.Errorf("shadow: %v", err)
+		}
+		shadowLogger, err := logger.New(filepath.Join(cfg.LogDir, "shadow.log"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize shadow logger: %v", err)
 		}
+		b.shadow, b.shadowLogger = shadow, shadowLogger
+		log.Printf("Shadow mood classifier enabled: %s", shadow.Name())
 	}
 
-	// Finally check for negative words
-	for _, word := range negativeWords {
-		if strings.Contains(text, word) {
-			return "negative"
+	return b, nil
+}
+
+// loadClassifier loads the trained model if a path is given, the lexicon otherwise
+func loadClassifier(modelPath, lexiconDir string) (mood.Classifier, error) {
+	if modelPath != "" {
+		model, err := mood.LoadBayes(modelPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load mood model: %v", err)
 		}
+		return model, nil
 	}
 
-	return "neutral"
+	analyzer, err := mood.NewAnalyzer(lexiconDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mood lexicon: %v", err)
+	}
+	return analyzer, nil
 }
 
-func (b *Bot) Run() error {
-	log.Printf("Authorized on account %s", b.api.Self.UserName)
-	defer b.logger.Close()
+// analyzeMood analyzes the text and returns the detected mood with its intensity
+// and the matched keywords that explain it.
+// Typos, stretched letters and translit are normalized by the analyzer.
+// Ambiguous texts come back as "neutral" so the retry loop asks for more details.
+func (b *Bot) analyzeMood(chatID int64, text string) mood.Result {
+	result := b.classifier.Analyze(text)
+	log.Printf("Mood scores: %v, confidence: %.2f, matches: %v", result.Scores, result.Confidence, result.Matches)
+	b.compareShadow(chatID, text, result)
+	return result
+}
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+// moodContextSize is how many recent answers are classified together;
+// it covers all the attempts of the "Расскажи мне побольше" loop
+const moodContextSize = 3
+
+// withContext adds the message to the recent answers of the chat and returns
+// them as one text, oldest first, together with the earlier answers alone.
+// Ответы разделяем точкой, чтобы отрицание из одного не переходило на другой.
+func (b *Bot) withContext(chatID int64, text string) (string, []string) {
+	window := append(b.moodContext[chatID], text)
+	if len(window) > moodContextSize {
+		window = window[len(window)-moodContextSize:]
+	}
+	b.moodContext[chatID] = window
+	return strings.Join(window, ". "), slices.Clone(window[:len(window)-1])
+}
 
-	updates := b.api.GetUpdatesChan(u)
+// resetMood forgets the attempts and the recent answers once the mood is known
+func (b *Bot) resetMood(chatID int64) {
+	delete(b.moodAttempts, chatID)
+	delete(b.moodContext, chatID)
+}
 
-	for update := range updates {
-		// Handle callback queries (button presses)
-		if update.CallbackQuery != nil {
-			callback := update.CallbackQuery
-			chatID := callback.Message.Chat.ID
-			username := callback.From.UserName
-			if username == "" {
-				username = fmt.Sprintf("User%d", chatID)
-			}
+// fire moves the dialog of the chat along the event. A missing transition is
+// a mistake in the handlers, so it is only logged.
+func (b *Bot) fire(chatID int64, event dialog.Event) {
+	if _, err := b.dialog.Fire(chatID, event); err != nil {
+		log.Printf("Error in dialog of chat %d: %v", chatID, err)
+	}
+}
 
-			var response string
-			switch callback.Data {
-			case "exercise1":
-				response = "Упражнение 1: Глубокое дыхание\n\n" +
-					"1. Сядьте удобно и расслабьтесь\n" +
-					"2. Сделайте глубокий вдох через нос на 4 счета\n" +
-					"3. Задержите дыхание на 4 счета\n" +
-					"4. Медленно выдохните через рот на 4 счета\n" +
-					"5. Повторите 5-7 раз\n\n" +
-					"Это упражнение поможет снять напряжение и восстановить энергию."
-			case "exercise2":
-				response = "Упражнение 2: Растяжка шеи\n\n" +
-					"1. Сядьте прямо\n" +
-					"2. Медленно наклоните голову вправо, задержитесь на 10 секунд\n" +
-					"3. Вернитесь в исходное положение\n" +
-					"4. Повторите влево\n" +
-					"5. Сделайте по 3-4 раза в каждую сторону\n\n" +
-					"Это упражнение поможет снять напряжение в шее и плечах."
-			case "exercise3":
-				response = "Упражнение 3: Мини-прогулка\n\n" +
-					"1. Встаньте и пройдитесь по комнате 2-3 минуты\n" +
-					"2. Делайте это в спокойном темпе\n" +
-					"3. Следите за дыханием\n" +
-					"4. Можно выйти на свежий воздух, если есть возможность\n\n" +
-					"Это упражнение поможет разогнать кровь и взбодриться."
-			case "exercise4":
-				response = "Упражнение 4: Гимнастика для глаз\n\n" +
-					"1. Закройте глаза на 10 секунд\n" +
-					"2. Откройте и посмотрите вдаль 10 секунд\n" +
-					"3. Сделайте круговые движения глазами по часовой стрелке\n" +
-					"4. Повторите против часовой стрелки\n" +
-					"5. Сделайте 3-4 подхода\n\n" +
-					"Это упражнение поможет снять напряжение с глаз и улучшить концентрацию."
-			case "mindfulness1":
-				response = "Практика 'Вижу, слышу, чувствую' 🌟\n\n" +
-					"Это простая и мощная техника осознанности (mindfulness), которая помогает вернуться в настоящий момент, заземлиться, снизить тревожность и выйти из потока мыслей.\n\n" +
-					"Как выполнять:\n" +
-					"1. Сядьте или встаньте спокойно\n" +
-					"2. Ненадолго остановитесь\n" +
-					"3. Начните замечать то, что происходит прямо сейчас\n\n" +
-					"Не нужно ничего анализировать, оценивать или 'делать правильно'. Только наблюдать и отмечать словами:\n\n" +
-					"Вижу...\n" +
-					"Слышу...\n" +
-					"Чувствую...\n\n" +
-					"Эта практика особенно полезна в повседневной жизни, когда хочется остановиться и просто быть."
-			case "mindfulness2":
-				response = "Практика 'ABC noting' 🌟\n\n" +
-					"Это простая, но мощная техника осознанности (mindfulness), которая помогает заметить, в какой 'зоне' ты находишься прямо сейчас, и перевести внимание из автоматического реагирования в осознанность.\n\n" +
-					"Как выполнять:\n\n" +
-					"A — Aware (осознаю):\n" +
-					"Заметь, что происходит прямо сейчас.\n\n" +
-					"B — Balance:\n" +
-					"Найди равновесие в своей позе.\n\n" +
-					"C - Concentrate:\n" +
-					"Вытянись вверх и расслабь лицо, шею, грудную клетку, диафрагму и живот. Оглянись и заметь себя и место, где ты находишься."
-			}
+// mixedEvent is the dialog event of a combined reply: it offers the tiredness
+// exercises when tiredness is among the moods
+func mixedEvent(result mood.Result) dialog.Event {
+	for _, m := range result.Moods {
+		if m.Mood == "tired" {
+			return dialog.OfferExercises
+		}
+	}
+	return dialog.Answer
+}
 
-			msg := tgbotapi.NewMessage(chatID, response)
-			if _, err := b.api.Send(msg); err != nil {
-				log.Printf("Error sending message: %v", err)
-			}
+// compareShadow runs the shadow classifier on the same text and records
+// the messages where it would have answered differently
+func (b *Bot) compareShadow(chatID int64, text string, primary mood.Result) {
+	if b.shadow == nil {
+		return
+	}
 
-			// Логируем ответ на callback
-			if err := b.logger.Log(chatID, username, "callback", callback.Data, response, ""); err != nil {
-				log.Printf("Error logging callback: %v", err)
-			}
+	shadow := b.shadow.Analyze(text)
+	if slices.Equal(moodNames(primary), moodNames(shadow)) && primary.Mood == shadow.Mood {
+		return
+	}
 
-			// Answer callback query to remove loading state
-			callbackConfig := tgbotapi.NewCallback(callback.ID, "")
-			if _, err := b.api.Request(callbackConfig); err != nil {
-				log.Printf("Error answering callback query: %v", err)
-			}
+	log.Printf("Shadow classifier disagrees: %s vs %s", primary.Mood, shadow.Mood)
+	if err := b.shadowLogger.LogShadow(chatID, text,
+		logger.NewShadowVerdict(b.classifier.Name(), primary),
+		logger.NewShadowVerdict(b.shadow.Name(), shadow),
+	); err != nil {
+		log.Printf("Error logging shadow disagreement: %v", err)
+	}
+}
 
-			continue
-		}
+// watcher is a classifier whose model can be reloaded from disk while the bot runs
+type watcher interface {
+	Watch(interval time.Duration) (stop func())
+}
 
-		if update.Message == nil {
-			continue
+// fullExercisesIntensity is the lowest tiredness that gets all four exercises
+const fullExercisesIntensity = mood.Moderate
+
+// exerciseRows returns the inline buttons offered for the mood
+func exerciseRows(detected string, intensity mood.Intensity) [][]tgbotapi.InlineKeyboardButton {
+	switch detected {
+	case "positive", "calm":
+		return [][]tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Вижу, слышу, чувствую", "mindfulness1"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("ABC noting", "mindfulness2"),
+			),
 		}
-
-		chatID := update.Message.Chat.ID
-		username := update.Message.From.UserName
-		if username == "" {
-			username = fmt.Sprintf("User%d", chatID)
+	case "anxiety":
+		// Заземление возвращает в настоящий момент, дыхание успокаивает тело
+		return [][]tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Упражнение 5", "exercise5"),
+				tgbotapi.NewInlineKeyboardButtonData("Упражнение 1", "exercise1"),
+			),
 		}
-		state := b.conversationStates[chatID]
-
-		// Handle voice messages
-		if update.Message.Voice != nil {
-			log.Printf("Received voice message from user %d", chatID)
-
-			// Download the voice message
-			voice := update.Message.Voice
-			file, err := b.api.GetFile(tgbotapi.FileConfig{FileID: voice.FileID})
-			if err != nil {
-				log.Printf("Error getting file: %v", err)
-				continue
+	case "anger":
+		return [][]tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Упражнение 6", "exercise6"),
+				tgbotapi.NewInlineKeyboardButtonData("Упражнение 3", "exercise3"),
+			),
+		}
+	case "sadness":
+		return [][]tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Упражнение 8", "exercise8"),
+				tgbotapi.NewInlineKeyboardButtonData("Упражнение 3", "exercise3"),
+			),
+		}
+	case "stress":
+		return [][]tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Упражнение 7", "exercise7"),
+				tgbotapi.NewInlineKeyboardButtonData("Упражнение 1", "exercise1"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Упражнение 2", "exercise2"),
+			),
+		}
+	case "tired", "negative":
+		if detected == "tired" && intensity < fullExercisesIntensity {
+			// Легкая усталость: хватит короткой паузы
+			return [][]tgbotapi.InlineKeyboardButton{
+				tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData("Упражнение 1", "exercise1"),
+					tgbotapi.NewInlineKeyboardButtonData("Упражнение 4", "exercise4"),
+				),
 			}
-			log.Printf("Got file info: %+v", file)
+		}
+		return [][]tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Упражнение 1", "exercise1"),
+				tgbotapi.NewInlineKeyboardButtonData("Упражнение 2", "exercise2"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Упражнение 3", "exercise3"),
+				tgbotapi.NewInlineKeyboardButtonData("Упражнение 4", "exercise4"),
+			),
+		}
+	}
+	return nil
+}
 
-			// Create temp directory if it doesn't exist
-			if err := os.MkdirAll("temp", 0755); err != nil {
-				log.Printf("Error creating temp directory: %v", err)
-				continue
-			}
+// tiredReply picks the reply and exercise buttons by how tired the user is
+func tiredReply(intensity mood.Intensity) (string, tgbotapi.InlineKeyboardMarkup) {
+	response := "Сожалею, что ты сейчас устал. Давай я предложу тебе 4 упражнения, которые помогут восстановиться."
+	switch {
+	case intensity < fullExercisesIntensity:
+		response = "Похоже, ты немного подустал. Вот пара коротких упражнений, чтобы взбодриться."
+	case intensity == mood.Strong:
+		response = "Похоже, ты совсем вымотан. 😔 Давай я предложу тебе 4 упражнения, которые помогут восстановиться, а потом постарайся как следует отдохнуть."
+	}
 
-			// Download the file
-			resp, err := http.Get(file.Link(b.api.Token))
-			if err != nil {
-				log.Printf("Error downloading file: %v", err)
-				continue
-			}
-			defer resp.Body.Close()
-			log.Printf("Downloaded file successfully")
-
-			// Save the file
-			audioPath := filepath.Join("temp", fmt.Sprintf("%s.ogg", voice.FileID))
-			out, err := os.Create(audioPath)
-			if err != nil {
-				log.Printf("Error creating file: %v", err)
-				continue
-			}
-			defer out.Close()
+	// После упражнений от усталости бот ждет выбора, поэтому от них можно отказаться
+	return response, tgbotapi.NewInlineKeyboardMarkup(append(exerciseRows("tired", intensity), skipRow())...)
+}
 
-			_, err = io.Copy(out, resp.Body)
-			if err != nil {
-				log.Printf("Error saving file: %v", err)
-				continue
-			}
-			log.Printf("Saved file to %s", audioPath)
+// categoryReplies answer the finer moods, each followed by its own exercises
+var categoryReplies = map[string]string{
+	"anxiety": "Похоже, тебе тревожно. Давай попробуем заземлиться — это помогает вернуться в настоящий момент. Выбери упражнение.",
+	"anger":   "Понимаю, тебя что-то сильно задело. 😤 Давай сначала выдохнем и немного остынем — вот упражнения.",
+	"sadness": "Мне жаль, что тебе грустно. 💙 Давай попробуем немного позаботиться о себе — вот что может помочь.",
+	"stress":  "Похоже, на тебя сейчас много всего навалилось. Давай на пару минут снимем напряжение — выбери упражнение.",
+	"calm":    "Здорово, что тебе спокойно. 🍃 Давай закрепим это состояние — вот практики осознанности.",
+}
 
-			// Log file info
-			fileInfo, err := os.Stat(audioPath)
-			if err != nil {
-				log.Printf("Error getting file info: %v", err)
-			} else {
-				log.Printf("Audio file size: %d bytes", fileInfo.Size())
-			}
+// categoryReply picks the reply and exercise buttons for a finer mood
+func categoryReply(detected string, intensity mood.Intensity) (string, tgbotapi.InlineKeyboardMarkup) {
+	return categoryReplies[detected], tgbotapi.NewInlineKeyboardMarkup(exerciseRows(detected, intensity)...)
+}
 
-			// Convert OGG to WAV
-			wavPath, err := speech.ConvertOggToWav(audioPath)
-			if err != nil {
-				log.Printf("Error converting audio: %v", err)
-				continue
+// moodAcknowledgements describe each mood inside a combined reply
+var moodAcknowledgements = map[string]string{
+	"energized": "в тебе много энергии",
+	"tired":     "ты устал",
+	"positive":  "у тебя хорошее настроение",
+	"negative":  "тебе сейчас нелегко",
+	"anxiety":   "тебе тревожно",
+	"anger":     "ты злишься",
+	"sadness":   "тебе грустно",
+	"stress":    "ты в стрессе",
+	"calm":      "тебе спокойно",
+}
+
+// pleasantMoods are the good moods. A combined reply contrasts them only with
+// the other moods: "ты устал, но при этом у тебя хорошее настроение", but
+// "в тебе много энергии и у тебя хорошее настроение".
+var pleasantMoods = map[string]bool{
+	"positive":  true,
+	"energized": true,
+	"calm":      true,
+}
+
+// combinedReply acknowledges every detected mood and merges their exercise buttons
+func combinedReply(result mood.Result) (string, tgbotapi.InlineKeyboardMarkup) {
+	response := "Слышу, что "
+	var rows [][]tgbotapi.InlineKeyboardButton
+	seen := make(map[string]bool)
+
+	for i, m := range result.Moods {
+		if i > 0 {
+			if pleasantMoods[m.Mood] == pleasantMoods[result.Moods[i-1].Mood] {
+				response += " и "
+			} else {
+				response += ", но при этом "
 			}
-			defer speech.CleanupAudioFiles(audioPath, wavPath)
-			log.Printf("Converted to WAV: %s", wavPath)
-
-			// Transcribe the audio
-			text, err := b.speechClient.TranscribeAudio(wavPath)
-			if err != nil {
-				log.Printf("Error transcribing audio: %v", err)
-				msg := tgbotapi.NewMessage(chatID, "Извините, не удалось распознать голосовое сообщение.")
-				if _, err := b.api.Send(msg); err != nil {
-					log.Printf("Error sending message: %v", err)
+		}
+		response += moodAcknowledgements[m.Mood]
+		for _, row := range exerciseRows(m.Mood, m.Intensity) {
+			var merged []tgbotapi.InlineKeyboardButton
+			for _, button := range row {
+				if !seen[*button.CallbackData] {
+					seen[*button.CallbackData] = true
+					merged = append(merged, button)
 				}
-				continue
 			}
-			log.Printf("Transcribed text: %s", text)
+			if len(merged) > 0 {
+				rows = append(rows, merged)
+			}
+		}
+	}
 
-			// Process the transcribed text as if it was a text message
-			text = strings.ToLower(text)
-			log.Printf("Processing mood for text: %s", text)
+	if mixedEvent(result) == dialog.OfferExercises {
+		rows = append(rows, skipRow())
+	}
 
-			// Анализируем настроение сразу после получения голосового сообщения
-			mood := analyzeMood(text)
-			log.Printf("Detected mood: %s", mood)
-			var response string
-			var msg tgbotapi.MessageConfig
+	response += "."
+	if len(rows) > 0 {
+		response += " Давай поддержим и то, и другое — вот упражнения на выбор."
+	}
 
-			// Увеличиваем счетчик попыток
-			b.moodAttempts[chatID]++
-			attempts := b.moodAttempts[chatID]
+	return response, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
 
-			// Если после 3 попыток не удалось определить настроение, считаем его нейтральным
-			if mood == "neutral" && attempts >= 3 {
-				mood = "neutral_final"
-			}
+// moodNames lists the detected moods for the log
+func moodNames(result mood.Result) []string {
+	names := make([]string, len(result.Moods))
+	for i, m := range result.Moods {
+		names[i] = m.Mood
+	}
+	return names
+}
 
-			switch mood {
-			case "energized":
-				response = "Отлично! 💪 Такая энергия - это здорово! Держи этот настрой и используй его для достижения своих целей!"
-				delete(b.moodAttempts, chatID)
-			case "tired":
-				response = "Сожалею, что ты сейчас устал. Давай я предложу тебе 4 упражнения, которые помогут восстановиться."
-				delete(b.moodAttempts, chatID)
-				// Create keyboard with exercise buttons
-				var keyboard = tgbotapi.NewInlineKeyboardMarkup(
-					tgbotapi.NewInlineKeyboardRow(
-						tgbotapi.NewInlineKeyboardButtonData("Упражнение 1", "exercise1"),
-						tgbotapi.NewInlineKeyboardButtonData("Упражнение 2", "exercise2"),
-					),
-					tgbotapi.NewInlineKeyboardRow(
-						tgbotapi.NewInlineKeyboardButtonData("Упражнение 3", "exercise3"),
-						tgbotapi.NewInlineKeyboardButtonData("Упражнение 4", "exercise4"),
-					),
-				)
-				msg = tgbotapi.NewMessage(chatID, response)
-				msg.ReplyMarkup = keyboard
-				if _, err := b.api.Send(msg); err != nil {
-					log.Printf("Error sending tired response: %v", err)
-				}
-				continue
-			case "positive":
-				response = "Рад слышать, что у тебя всё хорошо! 😊 Давай сохраним это настроение! Предлагаю сделать практику осознанности."
-				// Create keyboard with exercise buttons
-				var keyboard = tgbotapi.NewInlineKeyboardMarkup(
-					tgbotapi.NewInlineKeyboardRow(
-						tgbotapi.NewInlineKeyboardButtonData("Вижу, слышу, чувствую", "mindfulness1"),
-					),
-					tgbotapi.NewInlineKeyboardRow(
-						tgbotapi.NewInlineKeyboardButtonData("ABC noting", "mindfulness2"),
-					),
-				)
-				msg := tgbotapi.NewMessage(chatID, response)
-				msg.ReplyMarkup = keyboard
-				if _, err := b.api.Send(msg); err != nil {
-					log.Printf("Error sending positive response: %v", err)
-				}
-				delete(b.conversationStates, chatID)
-				delete(b.moodAttempts, chatID)
-				continue
-			case "negative":
-				response = "Мне жаль, что тебе сейчас нелегко. Давай я предложу тебе несколько упражнений, которые помогут улучшить настроение."
-				delete(b.moodAttempts, chatID)
-				// Create keyboard with exercise buttons
-				var keyboard = tgbotapi.NewInlineKeyboardMarkup(
-					tgbotapi.NewInlineKeyboardRow(
-						tgbotapi.NewInlineKeyboardButtonData("Упражнение 1", "exercise1"),
-						tgbotapi.NewInlineKeyboardButtonData("Упражнение 2", "exercise2"),
-					),
-					tgbotapi.NewInlineKeyboardRow(
-						tgbotapi.NewInlineKeyboardButtonData("Упражнение 3", "exercise3"),
-						tgbotapi.NewInlineKeyboardButtonData("Упражнение 4", "exercise4"),
-					),
-				)
-				msg = tgbotapi.NewMessage(chatID, response)
-				msg.ReplyMarkup = keyboard
-				if _, err := b.api.Send(msg); err != nil {
-					log.Printf("Error sending negative response: %v", err)
-				}
-				continue
-			case "neutral":
-				response = "Расскажи мне побольше."
-				msg = tgbotapi.NewMessage(chatID, response)
-				if _, err := b.api.Send(msg); err != nil {
-					log.Printf("Error sending neutral response: %v", err)
-				}
-				continue
-			case "neutral_final":
-				response = "Спасибо за ответ! Надеюсь, у тебя будет хороший день! 🌞"
-				delete(b.moodAttempts, chatID)
-			default:
-				response = "Расскажи мне побольше."
-			}
+// sendCombined answers a message that mentions several moods at once
+func (b *Bot) sendCombined(chatID int64, result mood.Result) string {
+	response, keyboard := combinedReply(result)
+	msg := tgbotapi.NewMessage(chatID, response)
+	if len(keyboard.InlineKeyboard) > 0 {
+		msg.ReplyMarkup = keyboard
+	}
+	if _, err := b.api.Send(msg); err != nil {
+		log.Printf("Error sending combined response: %v", err)
+	}
+	return response
+}
 
-			msg = tgbotapi.NewMessage(chatID, response)
-			if _, err := b.api.Send(msg); err != nil {
-				log.Printf("Error sending final response: %v", err)
-			}
+func (b *Bot) Run() error {
+	defer b.Close()
 
-			// Логируем голосовое сообщение и ответ
-			if err := b.logger.Log(chatID, username, "voice", text, response, mood); err != nil {
-				log.Printf("Error logging voice message: %v", err)
-			}
+	// Словари перечитываются по SIGHUP или при изменении файлов, не прерывая опрос.
+	// Теневой классификатор со своим словарем перечитывается так же.
+	for _, c := range []mood.Classifier{b.classifier, b.shadow} {
+		if w, ok := c.(watcher); ok {
+			defer w.Watch(lexiconPollInterval)()
+		}
+	}
+
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
 
-			continue
+	// Команды появятся в меню клиента Telegram
+	b.registerCommands()
+
+	updates := b.api.GetUpdatesChan(u)
+
+	for {
+		// Все обновления и склеенные сообщения обрабатываются в этом цикле по очереди,
+		// поэтому состояния диалогов не нужно защищать от гонок
+		select {
+		case chatID := <-b.flushes:
+			b.flush(chatID)
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			b.HandleUpdate(update)
+		case <-b.stop:
+			// Собранные сообщения отвечаем сразу, чтобы они не пропали при остановке
+			b.FlushPending()
+			return nil
 		}
+	}
+}
 
-		// Handle text messages
-		if !update.Message.IsCommand() {
-			text := strings.ToLower(update.Message.Text)
+// Stop stops receiving updates and makes Run return after answering the
+// messages it has already received. Updates Telegram sent during the last
+// poll are not confirmed, so they come again after a restart.
+func (b *Bot) Stop() {
+	b.stopOnce.Do(func() {
+		b.api.StopReceivingUpdates()
+		close(b.stop)
+	})
+}
 
-			switch {
-			case (strings.Contains(text, "привет") || text == "/start") && state == "":
-				// Reset mood attempts counter
-				delete(b.moodAttempts, chatID)
+// Close stops the pending debounce timers and closes the logs
+func (b *Bot) Close() {
+	select {
+	case <-b.done:
+		return
+	default:
+		close(b.done)
+	}
+	b.stopBatches()
+	b.logger.Close()
+	b.feedbackLogger.Close()
+	if b.shadowLogger != nil {
+		b.shadowLogger.Close()
+	}
+}
 
-				// Send greeting
-				msg := tgbotapi.NewMessage(chatID, "Привет! 👋")
-				if _, err := b.api.Send(msg); err != nil {
-					log.Printf("Error sending message: %v", err)
-				}
+// State returns the dialog state of the chat
+func (b *Bot) State(chatID int64) dialog.State {
+	return b.dialog.State(chatID)
+}
 
-				// Ask how are you
-				howAreYouMsg := tgbotapi.NewMessage(chatID, "Как ты сейчас?")
-				if _, err := b.api.Send(howAreYouMsg); err != nil {
-					log.Printf("Error sending message: %v", err)
-				}
+// SetTranscriber replaces the speech recognition of voice messages, e.g. with
+// a stub when there is no Deepgram key
+func (b *Bot) SetTranscriber(t speech.Transcriber) {
+	b.transcriber = t
+}
 
-				// Логируем приветствие
-				if err := b.logger.Log(chatID, username, "text", text, "Привет! 👋\nКак ты сейчас?", ""); err != nil {
-					log.Printf("Error logging greeting: %v", err)
-				}
+// SetClock replaces the clock of the dialog timeouts
+func (b *Bot) SetClock(now func() time.Time) {
+	b.dialog.SetClock(now)
+}
 
-				b.conversationStates[chatID] = "waiting_for_mood"
+// HandleUpdate answers one update from Telegram. Run calls it for every update;
+// tools and scripted conversations can call it directly, one update at a time.
+func (b *Bot) HandleUpdate(update tgbotapi.Update) {
+	// Handle callback queries (button presses)
+	if update.CallbackQuery != nil {
+		callback := update.CallbackQuery
+		chatID := callback.Message.Chat.ID
+		username := callback.From.UserName
+		if username == "" {
+			username = fmt.Sprintf("User%d", chatID)
+		}
 
-			case state == "waiting_for_mood":
-				mood := analyzeMood(text)
-				var response string
+		if strings.HasPrefix(callback.Data, feedbackPrefix) {
+			b.handleFeedback(callback, username)
+			return
+		}
 
-				// Увеличиваем счетчик попыток
-				b.moodAttempts[chatID]++
-				attempts := b.moodAttempts[chatID]
+		response := exercises[callback.Data]
+		if callback.Data == skipExercise {
+			response = skipReply
+		}
 
-				// Если после 3 попыток не удалось определить настроение, считаем его нейтральным
-				if mood == "neutral" && attempts >= 3 {
-					mood = "neutral_final"
-				}
+		msg := tgbotapi.NewMessage(chatID, response)
+		if _, err := b.api.Send(msg); err != nil {
+			log.Printf("Error sending message: %v", err)
+		}
 
-				switch mood {
-				case "energized":
-					response = "Отлично! 💪 Такая энергия - это здорово! Держи этот настрой и используй его для достижения своих целей!"
-					msg := tgbotapi.NewMessage(chatID, response)
-					if _, err := b.api.Send(msg); err != nil {
-						log.Printf("Error sending message: %v", err)
-					}
-					delete(b.conversationStates, chatID)
-					delete(b.moodAttempts, chatID)
-
-				case "tired":
-					response = "Сожалею, что ты сейчас устал. Давай я предложу тебе 4 упражнения, которые помогут восстановиться."
-					msg := tgbotapi.NewMessage(chatID, response)
-
-					// Create keyboard with exercise buttons
-					var keyboard = tgbotapi.NewInlineKeyboardMarkup(
-						tgbotapi.NewInlineKeyboardRow(
-							tgbotapi.NewInlineKeyboardButtonData("Упражнение 1", "exercise1"),
-							tgbotapi.NewInlineKeyboardButtonData("Упражнение 2", "exercise2"),
-						),
-						tgbotapi.NewInlineKeyboardRow(
-							tgbotapi.NewInlineKeyboardButtonData("Упражнение 3", "exercise3"),
-							tgbotapi.NewInlineKeyboardButtonData("Упражнение 4", "exercise4"),
-						),
-					)
-
-					msg.ReplyMarkup = keyboard
-					if _, err := b.api.Send(msg); err != nil {
-						log.Printf("Error sending message: %v", err)
-					}
-					b.conversationStates[chatID] = "waiting_for_exercise"
-					delete(b.moodAttempts, chatID)
-
-				case "positive":
-					response = "Рад слышать, что у тебя всё хорошо! 😊 Давай сохраним это настроение! Предлагаю сделать практику осознанности."
-					// Create keyboard with exercise buttons
-					var keyboard = tgbotapi.NewInlineKeyboardMarkup(
-						tgbotapi.NewInlineKeyboardRow(
-							tgbotapi.NewInlineKeyboardButtonData("Вижу, слышу, чувствую", "mindfulness1"),
-						),
-						tgbotapi.NewInlineKeyboardRow(
-							tgbotapi.NewInlineKeyboardButtonData("ABC noting", "mindfulness2"),
-						),
-					)
-					msg := tgbotapi.NewMessage(chatID, response)
-					msg.ReplyMarkup = keyboard
-					if _, err := b.api.Send(msg); err != nil {
-						log.Printf("Error sending positive response: %v", err)
-					}
-					delete(b.conversationStates, chatID)
-					delete(b.moodAttempts, chatID)
-					continue
-
-				case "negative":
-					response = "Понимаю, что сейчас не лучший момент. 🌟 Надеюсь, скоро всё наладится! Может, стоит сделать что-то приятное для себя?"
-					msg := tgbotapi.NewMessage(chatID, response)
-					if _, err := b.api.Send(msg); err != nil {
-						log.Printf("Error sending message: %v", err)
-					}
-					delete(b.conversationStates, chatID)
-					delete(b.moodAttempts, chatID)
-
-				case "neutral":
-					response = "Расскажи мне побольше."
-					msg := tgbotapi.NewMessage(chatID, response)
-					if _, err := b.api.Send(msg); err != nil {
-						log.Printf("Error sending message: %v", err)
-					}
-
-				case "neutral_final":
-					response = "Спасибо за ответ! Надеюсь, у тебя будет хороший день! 🌞"
-					msg := tgbotapi.NewMessage(chatID, response)
-					if _, err := b.api.Send(msg); err != nil {
-						log.Printf("Error sending message: %v", err)
-					}
-					delete(b.conversationStates, chatID)
-					delete(b.moodAttempts, chatID)
-
-				default:
-					response = "Расскажи мне побольше."
-					msg := tgbotapi.NewMessage(chatID, response)
-					if _, err := b.api.Send(msg); err != nil {
-						log.Printf("Error sending message: %v", err)
-					}
-				}
+		// Выбор упражнения или отказ завершает ожидание, если бот его предлагал
+		switch {
+		case callback.Data == skipExercise && b.dialog.Can(chatID, dialog.Skip):
+			b.fire(chatID, dialog.Skip)
+		case response != "" && b.dialog.Can(chatID, dialog.ChooseExercise):
+			b.fire(chatID, dialog.ChooseExercise)
+		}
 
-				// Логируем текстовое сообщение и ответ
-				if err := b.logger.Log(chatID, username, "text", text, response, mood); err != nil {
-					log.Printf("Error logging text message: %v", err)
-				}
-			}
+		// Логируем ответ на callback
+		if err := b.logger.Log(chatID, username, "callback", callback.Data, response); err != nil {
+			log.Printf("Error logging callback: %v", err)
 		}
+
+		// Answer callback query to remove loading state
+		callbackConfig := tgbotapi.NewCallback(callback.ID, "")
+		if _, err := b.api.Request(callbackConfig); err != nil {
+			log.Printf("Error answering callback query: %v", err)
+		}
+
+		return
 	}
 
-	return nil
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	username := update.Message.From.UserName
+	if username == "" {
+		username = fmt.Sprintf("User%d", chatID)
+	}
+
+	// Голосовое сразу переводим в текст, дальше оно идет тем же путем, что и текст
+	if update.Message.Voice != nil {
+		log.Printf("Received voice message from user %d", chatID)
+		text, err := b.transcribeVoice(update.Message.Voice)
+		if err != nil {
+			log.Printf("Error processing voice message: %v", err)
+			b.send(chatID, "Извините, не удалось распознать голосовое сообщение.", nil)
+			return
+		}
+		b.debounce(chatID, username, "voice", text)
+		return
+	}
+
+	// Команды обрабатываются сразу, после накопленного перед ними текста
+	if update.Message.IsCommand() {
+		b.flush(chatID)
+		b.handleCommand(chatID, username, update.Message)
+		return
+	}
+
+	// Handle text messages
+	text := strings.ToLower(update.Message.Text)
+	messageType := "text"
+	// Стикер анализируем по связанному с ним эмодзи
+	if update.Message.Sticker != nil {
+		text = update.Message.Sticker.Emoji
+		messageType = "sticker"
+		log.Printf("Received sticker from user %d: %s", chatID, text)
+	}
+	// Фото, документы и прочее без текста не разбираем
+	if text == "" {
+		return
+	}
+
+	b.debounce(chatID, username, messageType, text)
+}
+
+// greet sends the greeting and asks how the user is; returns the reply for the log
+func (b *Bot) greet(chatID int64) string {
+	// Send greeting
+	msg := tgbotapi.NewMessage(chatID, "Привет! 👋")
+	if _, err := b.api.Send(msg); err != nil {
+		log.Printf("Error sending message: %v", err)
+	}
+
+	// Ask how are you
+	howAreYouMsg := tgbotapi.NewMessage(chatID, "Как ты сейчас?")
+	if _, err := b.api.Send(howAreYouMsg); err != nil {
+		log.Printf("Error sending message: %v", err)
+	}
+
+	return "Привет! 👋\nКак ты сейчас?"
 }
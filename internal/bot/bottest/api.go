@@ -0,0 +1,230 @@
+// Package bottest provides an in-memory fake of the Telegram Bot API for
+// exercising the bot handlers without network.
+package bottest
+
+import (
+	"fmt"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Message is a message the bot sent. Keyboard follows later edits, so after
+// the feedback buttons are removed it is empty.
+type Message struct {
+	ID       int                               `json:"id"`
+	ChatID   int64                             `json:"chat_id"`
+	Text     string                            `json:"text"`
+	Keyboard [][]tgbotapi.InlineKeyboardButton `json:"keyboard,omitempty"`
+}
+
+// API implements bot.API in memory. It records the sent messages and the other
+// requests, and Inject delivers updates to the channel the bot reads in Run.
+type API struct {
+	mu       sync.Mutex
+	messages []*Message
+	requests []tgbotapi.Chattable
+	// files are the download links of files by ID, see AddFile
+	files   map[string]string
+	updates chan tgbotapi.Update
+	stopped bool
+	// nextUpdateID numbers injected updates like Telegram does
+	nextUpdateID int
+}
+
+// NewAPI creates an empty fake
+func NewAPI() *API {
+	return &API{
+		files:   make(map[string]string),
+		updates: make(chan tgbotapi.Update, 100),
+	}
+}
+
+// Send records a message. Only text messages are supported, as the bot sends nothing else.
+func (a *API) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	msg, ok := c.(tgbotapi.MessageConfig)
+	if !ok {
+		return tgbotapi.Message{}, fmt.Errorf("bottest: unsupported message %T", c)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	m := &Message{ID: len(a.messages) + 1, ChatID: msg.ChatID, Text: msg.Text}
+	if keyboard, ok := msg.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup); ok {
+		m.Keyboard = keyboard.InlineKeyboard
+	}
+	a.messages = append(a.messages, m)
+	return tgbotapi.Message{MessageID: m.ID, Chat: &tgbotapi.Chat{ID: m.ChatID}, Text: m.Text}, nil
+}
+
+// Request records a call. Keyboard edits also change the recorded message.
+func (a *API) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.requests = append(a.requests, c)
+
+	if edit, ok := c.(tgbotapi.EditMessageReplyMarkupConfig); ok {
+		m := a.message(edit.ChatID, edit.MessageID)
+		if m == nil {
+			return nil, fmt.Errorf("bottest: message %d not found in chat %d", edit.MessageID, edit.ChatID)
+		}
+		m.Keyboard = nil
+		if edit.ReplyMarkup != nil {
+			m.Keyboard = edit.ReplyMarkup.InlineKeyboard
+		}
+	}
+	return &tgbotapi.APIResponse{Ok: true, Result: []byte("true")}, nil
+}
+
+// GetFileDirectURL returns the link added with AddFile
+func (a *API) GetFileDirectURL(fileID string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	link, ok := a.files[fileID]
+	if !ok {
+		return "", fmt.Errorf("bottest: file %s not found", fileID)
+	}
+	return link, nil
+}
+
+// GetUpdatesChan returns the channel of the injected updates
+func (a *API) GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+	return a.updates
+}
+
+// StopReceivingUpdates closes the updates channel, so Run returns
+func (a *API) StopReceivingUpdates() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.stopped {
+		a.stopped = true
+		close(a.updates)
+	}
+}
+
+// AddFile makes a file, e.g. a voice message, downloadable by its ID
+func (a *API) AddFile(fileID, link string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.files[fileID] = link
+}
+
+// Inject delivers an update to Run, numbering it if it has no ID
+func (a *API) Inject(update tgbotapi.Update) {
+	a.mu.Lock()
+	a.nextUpdateID++
+	if update.UpdateID == 0 {
+		update.UpdateID = a.nextUpdateID
+	}
+	a.mu.Unlock()
+	a.updates <- update
+}
+
+// Messages returns copies of the messages the bot sent, oldest first
+func (a *API) Messages() []Message {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	messages := make([]Message, len(a.messages))
+	for i, m := range a.messages {
+		messages[i] = *m
+	}
+	return messages
+}
+
+// ChatMessages returns the messages the bot sent to one chat
+func (a *API) ChatMessages(chatID int64) []Message {
+	var messages []Message
+	for _, m := range a.Messages() {
+		if m.ChatID == chatID {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+// Requests returns the calls other than sent messages: callback answers,
+// keyboard edits and the command menu
+func (a *API) Requests() []tgbotapi.Chattable {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]tgbotapi.Chattable(nil), a.requests...)
+}
+
+// FindButton returns the newest message of the chat that still shows a button
+// with the label, and the button
+func (a *API) FindButton(chatID int64, label string) (Message, tgbotapi.InlineKeyboardButton, bool) {
+	messages := a.ChatMessages(chatID)
+	for i := len(messages) - 1; i >= 0; i-- {
+		for _, row := range messages[i].Keyboard {
+			for _, b := range row {
+				if b.Text == label {
+					return messages[i], b, true
+				}
+			}
+		}
+	}
+	return Message{}, tgbotapi.InlineKeyboardButton{}, false
+}
+
+func (a *API) message(chatID int64, id int) *Message {
+	for _, m := range a.messages {
+		if m.ID == id && m.ChatID == chatID {
+			return m
+		}
+	}
+	return nil
+}
+
+// TextMessage builds an update with a text message from the user of the
+// chat. A leading slash makes it a command, as the Telegram client does.
+func TextMessage(chatID int64, text string) tgbotapi.Update {
+	m := &tgbotapi.Message{
+		From: user(chatID),
+		Chat: &tgbotapi.Chat{ID: chatID},
+		Text: text,
+	}
+	if len(text) > 1 && text[0] == '/' {
+		length := len(text)
+		for i, r := range text {
+			if r == ' ' {
+				length = i
+				break
+			}
+		}
+		m.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: length}}
+	}
+	return tgbotapi.Update{Message: m}
+}
+
+// Sticker builds an update with a sticker
+func Sticker(chatID int64, emoji string) tgbotapi.Update {
+	return tgbotapi.Update{Message: &tgbotapi.Message{
+		From:    user(chatID),
+		Chat:    &tgbotapi.Chat{ID: chatID},
+		Sticker: &tgbotapi.Sticker{Emoji: emoji},
+	}}
+}
+
+// Voice builds an update with a voice message; its file must be added with AddFile
+func Voice(chatID int64, fileID string) tgbotapi.Update {
+	return tgbotapi.Update{Message: &tgbotapi.Message{
+		From:  user(chatID),
+		Chat:  &tgbotapi.Chat{ID: chatID},
+		Voice: &tgbotapi.Voice{FileID: fileID},
+	}}
+}
+
+// Press builds an update with a press of the button under the message
+func Press(chatID int64, messageID int, data string) tgbotapi.Update {
+	return tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      fmt.Sprintf("%d-%d-%s", chatID, messageID, data),
+		From:    user(chatID),
+		Message: &tgbotapi.Message{MessageID: messageID, Chat: &tgbotapi.Chat{ID: chatID}},
+		Data:    data,
+	}}
+}
+
+// user is the sender of the updates of a private chat, which has the user's ID
+func user(chatID int64) *tgbotapi.User {
+	return &tgbotapi.User{ID: chatID, UserName: fmt.Sprintf("user%d", chatID)}
+}
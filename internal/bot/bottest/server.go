@@ -0,0 +1,360 @@
+package bottest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Server is a stand-in for api.telegram.org. It serves the Bot API methods the
+// bot uses (getMe, getUpdates, sendMessage, editMessageReplyMarkup,
+// answerCallbackQuery, setMyCommands, getFile) and file downloads under the
+// same paths, so bot.NewAPI, or TELEGRAM_API_URL for cmd/bot, can point at it.
+//
+// Tests in the same process script it with Inject, AddFile and WaitMessages.
+// Other processes use the control API under /_test/:
+//
+//	POST /_test/updates                       inject the JSON update in the body
+//	POST /_test/files?file_id=ID&path=voice.ogg  make the body downloadable as a file
+//	GET  /_test/messages?chat_id=1&count=2&wait=5s  the sent messages, waiting for count of them
+//	GET  /_test/requests                      every Bot API call with its parameters
+type Server struct {
+	// token is the only accepted bot token; empty accepts any
+	token string
+
+	mu       sync.Mutex
+	messages []*Message
+	requests []Request
+	updates  []tgbotapi.Update
+	files    map[string]serverFile
+	// nextUpdateID numbers injected updates like Telegram does
+	nextUpdateID int
+	// changed is closed and replaced whenever an update or a message arrives,
+	// waking the long polls and WaitMessages
+	changed chan struct{}
+}
+
+// Request is a Bot API call the server received
+type Request struct {
+	Method string     `json:"method"`
+	Params url.Values `json:"params"`
+}
+
+type serverFile struct {
+	path string
+	data []byte
+}
+
+// NewServer creates a server that accepts the bot token; empty accepts any
+func NewServer(token string) *Server {
+	return &Server{
+		token:   token,
+		files:   make(map[string]serverFile),
+		changed: make(chan struct{}),
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch p := r.URL.Path; {
+	case strings.HasPrefix(p, "/_test/"):
+		s.serveControl(w, r, strings.TrimPrefix(p, "/_test/"))
+	case strings.HasPrefix(p, "/file/bot"):
+		token, path, _ := strings.Cut(strings.TrimPrefix(p, "/file/bot"), "/")
+		s.serveFile(w, token, path)
+	case strings.HasPrefix(p, "/bot"):
+		token, method, _ := strings.Cut(strings.TrimPrefix(p, "/bot"), "/")
+		s.serveMethod(w, r, token, method)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveMethod answers a Bot API call like Telegram: {"ok": true, "result": ...}
+// or {"ok": false, "error_code": ..., "description": ...}
+func (s *Server) serveMethod(w http.ResponseWriter, r *http.Request, token, method string) {
+	if s.token != "" && token != s.token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Bad Request: %v", err))
+		return
+	}
+	params := r.Form
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: method, Params: params})
+	s.mu.Unlock()
+
+	var result any
+	var err error
+	switch method {
+	case "getMe":
+		result = tgbotapi.User{ID: 1, IsBot: true, FirstName: "Fake bot", UserName: "fake_bot"}
+	case "getUpdates":
+		result, err = s.getUpdates(r, params)
+	case "sendMessage":
+		result, err = s.sendMessage(params)
+	case "editMessageReplyMarkup":
+		result, err = s.editMessageReplyMarkup(params)
+	case "answerCallbackQuery", "setMyCommands", "deleteMyCommands":
+		result = true
+	case "getFile":
+		result, err = s.getFile(params)
+	default:
+		writeError(w, http.StatusNotFound, "Not Found: method "+method+" is not supported by the fake server")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "result": result})
+}
+
+// getUpdates returns the updates from offset on, waiting up to timeout
+// seconds for one to arrive. Updates before offset are confirmed and dropped.
+func (s *Server) getUpdates(r *http.Request, params url.Values) ([]tgbotapi.Update, error) {
+	offset, _ := strconv.Atoi(params.Get("offset"))
+	timeout, _ := strconv.Atoi(params.Get("timeout"))
+	deadline := time.After(time.Duration(timeout) * time.Second)
+
+	for {
+		s.mu.Lock()
+		kept := s.updates[:0]
+		for _, u := range s.updates {
+			if u.UpdateID >= offset {
+				kept = append(kept, u)
+			}
+		}
+		s.updates = kept
+		updates := append([]tgbotapi.Update{}, kept...)
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(updates) > 0 || timeout <= 0 {
+			return updates, nil
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return updates, nil
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+	}
+}
+
+func (s *Server) sendMessage(params url.Values) (tgbotapi.Message, error) {
+	chatID, err := strconv.ParseInt(params.Get("chat_id"), 10, 64)
+	if err != nil {
+		return tgbotapi.Message{}, fmt.Errorf("chat_id is invalid")
+	}
+	keyboard, err := parseKeyboard(params.Get("reply_markup"))
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	s.mu.Lock()
+	m := &Message{ID: len(s.messages) + 1, ChatID: chatID, Text: params.Get("text"), Keyboard: keyboard}
+	s.messages = append(s.messages, m)
+	s.notify()
+	s.mu.Unlock()
+
+	return tgbotapi.Message{
+		MessageID: m.ID,
+		Date:      int(time.Now().Unix()),
+		Chat:      &tgbotapi.Chat{ID: chatID, Type: "private"},
+		Text:      m.Text,
+	}, nil
+}
+
+func (s *Server) editMessageReplyMarkup(params url.Values) (tgbotapi.Message, error) {
+	chatID, _ := strconv.ParseInt(params.Get("chat_id"), 10, 64)
+	id, _ := strconv.Atoi(params.Get("message_id"))
+	keyboard, err := parseKeyboard(params.Get("reply_markup"))
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.messages {
+		if m.ID == id && m.ChatID == chatID {
+			m.Keyboard = keyboard
+			return tgbotapi.Message{MessageID: m.ID, Chat: &tgbotapi.Chat{ID: chatID, Type: "private"}, Text: m.Text}, nil
+		}
+	}
+	return tgbotapi.Message{}, fmt.Errorf("message to edit not found")
+}
+
+func (s *Server) getFile(params url.Values) (tgbotapi.File, error) {
+	fileID := params.Get("file_id")
+	s.mu.Lock()
+	f, ok := s.files[fileID]
+	s.mu.Unlock()
+	if !ok {
+		return tgbotapi.File{}, fmt.Errorf("invalid file_id")
+	}
+	return tgbotapi.File{FileID: fileID, FileUniqueID: fileID, FileSize: len(f.data), FilePath: f.path}, nil
+}
+
+// serveFile serves a file download, like https://api.telegram.org/file/bot<token>/<path>
+func (s *Server) serveFile(w http.ResponseWriter, token, path string) {
+	if s.token != "" && token != s.token {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range s.files {
+		if f.path == path {
+			w.Write(f.data)
+			return
+		}
+	}
+	http.Error(w, "Not Found", http.StatusNotFound)
+}
+
+// serveControl serves the /_test/ API that scripts the server from other processes
+func (s *Server) serveControl(w http.ResponseWriter, r *http.Request, action string) {
+	switch {
+	case action == "updates" && r.Method == http.MethodPost:
+		var update tgbotapi.Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, fmt.Sprintf("invalid update: %v", err), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.Inject(update))
+	case action == "files" && r.Method == http.MethodPost:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read file: %v", err), http.StatusBadRequest)
+			return
+		}
+		fileID := r.URL.Query().Get("file_id")
+		path := r.URL.Query().Get("path")
+		if fileID == "" || path == "" {
+			http.Error(w, "file_id and path are required", http.StatusBadRequest)
+			return
+		}
+		s.AddFile(fileID, path, data)
+		w.WriteHeader(http.StatusNoContent)
+	case action == "messages" && r.Method == http.MethodGet:
+		query := r.URL.Query()
+		chatID, _ := strconv.ParseInt(query.Get("chat_id"), 10, 64)
+		count, _ := strconv.Atoi(query.Get("count"))
+		wait, _ := time.ParseDuration(query.Get("wait"))
+		messages, err := s.WaitMessages(chatID, count, wait)
+		if err != nil {
+			writeJSON(w, http.StatusRequestTimeout, messages)
+			return
+		}
+		writeJSON(w, http.StatusOK, messages)
+	case action == "requests" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.Requests())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Inject queues an update for getUpdates, numbering it if it has no ID, and
+// returns it
+func (s *Server) Inject(update tgbotapi.Update) tgbotapi.Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextUpdateID++
+	if update.UpdateID == 0 {
+		update.UpdateID = s.nextUpdateID
+	}
+	s.updates = append(s.updates, update)
+	s.notify()
+	return update
+}
+
+// AddFile makes data downloadable by the file ID, e.g. the .ogg of an
+// injected Voice update
+func (s *Server) AddFile(fileID, path string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[fileID] = serverFile{path: path, data: data}
+}
+
+// Messages returns copies of the messages sent to the chat, or to every chat
+// if chatID is 0, oldest first
+func (s *Server) Messages(chatID int64) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := []Message{}
+	for _, m := range s.messages {
+		if chatID == 0 || m.ChatID == chatID {
+			messages = append(messages, *m)
+		}
+	}
+	return messages
+}
+
+// WaitMessages waits until the bot has sent count messages to the chat (any
+// chat if chatID is 0) and returns them. After the timeout it returns the
+// messages sent so far with an error.
+func (s *Server) WaitMessages(chatID int64, count int, timeout time.Duration) ([]Message, error) {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+
+		messages := s.Messages(chatID)
+		if len(messages) >= count {
+			return messages, nil
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return messages, fmt.Errorf("got %d of %d messages in %v", len(messages), count, timeout)
+		}
+	}
+}
+
+// Requests returns every Bot API call the server received, oldest first
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+// notify wakes everyone waiting for a change; s.mu must be held
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// parseKeyboard reads the inline buttons of a reply_markup parameter
+func parseKeyboard(markup string) ([][]tgbotapi.InlineKeyboardButton, error) {
+	if markup == "" {
+		return nil, nil
+	}
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(markup), &keyboard); err != nil {
+		return nil, fmt.Errorf("reply_markup is invalid: %v", err)
+	}
+	return keyboard.InlineKeyboard, nil
+}
+
+func writeError(w http.ResponseWriter, code int, description string) {
+	writeJSON(w, code, map[string]any{"ok": false, "error_code": code, "description": description})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"tg_bot/internal/dialog"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// command is a bot command with its help text. The handler sends the replies
+// itself and returns them for the log, or "" when it has logged the message
+// already, like /mood with a text answered as a mood.
+type command struct {
+	name string
+	// args describes the optional arguments in /help, e.g. "[текст]"
+	args        string
+	description string
+	handle      func(b *Bot, chatID int64, username string, args []string) string
+}
+
+// commandList is the registry of the bot commands in /help and menu order
+func commandList() []command {
+	return []command{
+		{name: "start", description: "Начать разговор заново", handle: (*Bot).startCommand},
+		{name: "mood", args: "[текст]", description: "Рассказать, как ты себя чувствуешь", handle: (*Bot).moodCommand},
+		{name: "exercises", description: "Все упражнения и практики", handle: (*Bot).exercisesCommand},
+		{name: "stop", description: "Закончить разговор", handle: (*Bot).stopCommand},
+		{name: "help", description: "Что умеет бот", handle: (*Bot).helpCommand},
+	}
+}
+
+// findCommand looks a command up by name, without the leading slash
+func findCommand(name string) (command, bool) {
+	for _, c := range commandList() {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// registerCommands shows the commands in the menu of the Telegram client
+func (b *Bot) registerCommands() {
+	var menu []tgbotapi.BotCommand
+	for _, c := range commandList() {
+		menu = append(menu, tgbotapi.BotCommand{Command: c.name, Description: c.description})
+	}
+	if _, err := b.api.Request(tgbotapi.NewSetMyCommands(menu...)); err != nil {
+		log.Printf("Error registering bot commands: %v", err)
+	}
+}
+
+// handleCommand runs the command of the message with its space-separated arguments
+func (b *Bot) handleCommand(chatID int64, username string, message *tgbotapi.Message) {
+	name := message.Command()
+	args := strings.Fields(message.CommandArguments())
+	log.Printf("Received command /%s %v from user %d", name, args, chatID)
+
+	var response string
+	if c, ok := findCommand(name); ok {
+		response = c.handle(b, chatID, username, args)
+	} else {
+		response = "Не знаю такой команды. Вот что я умею:\n\n" + helpText()
+		b.send(chatID, response, nil)
+	}
+
+	if response == "" {
+		return
+	}
+	if err := b.logger.Log(chatID, username, "command", message.Text, response); err != nil {
+		log.Printf("Error logging command: %v", err)
+	}
+}
+
+// restart forgets the conversation of the chat and waits for the mood again
+func (b *Bot) restart(chatID int64) {
+	delete(b.pendingFeedback, chatID)
+	b.fire(chatID, dialog.Stop)
+	b.fire(chatID, dialog.Greet)
+}
+
+// startCommand greets the user. A deep link payload (t.me/bot?start=exercises)
+// that names another command runs that command instead.
+func (b *Bot) startCommand(chatID int64, username string, args []string) string {
+	if len(args) > 0 && args[0] != "start" {
+		if c, ok := findCommand(args[0]); ok {
+			return c.handle(b, chatID, username, args[1:])
+		}
+		log.Printf("Unknown /start payload %q from user %d", args[0], chatID)
+	}
+
+	response := b.greet(chatID)
+	b.restart(chatID)
+	return response
+}
+
+// moodCommand asks how the user is, or with a text answers it right away
+func (b *Bot) moodCommand(chatID int64, username string, args []string) string {
+	b.restart(chatID)
+	if len(args) == 0 {
+		response := "Как ты сейчас?"
+		b.send(chatID, response, nil)
+		return response
+	}
+
+	// Текст после команды разбираем как ответ на "Как ты сейчас?", запись в лог делает answerMood
+	b.handleMessage(chatID, username, "text", strings.ToLower(strings.Join(args, " ")))
+	return ""
+}
+
+// exercisesCommand offers every exercise and practice
+func (b *Bot) exercisesCommand(chatID int64, username string, args []string) string {
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Упражнение 1", "exercise1"),
+			tgbotapi.NewInlineKeyboardButtonData("Упражнение 2", "exercise2"),
+			tgbotapi.NewInlineKeyboardButtonData("Упражнение 3", "exercise3"),
+			tgbotapi.NewInlineKeyboardButtonData("Упражнение 4", "exercise4"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Упражнение 5", "exercise5"),
+			tgbotapi.NewInlineKeyboardButtonData("Упражнение 6", "exercise6"),
+			tgbotapi.NewInlineKeyboardButtonData("Упражнение 7", "exercise7"),
+			tgbotapi.NewInlineKeyboardButtonData("Упражнение 8", "exercise8"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вижу, слышу, чувствую", "mindfulness1"),
+			tgbotapi.NewInlineKeyboardButtonData("ABC noting", "mindfulness2"),
+		),
+		skipRow(),
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+
+	response := "Вот все упражнения и практики: дыхание (1, 6), растяжка шеи (2), мини-прогулка (3), " +
+		"гимнастика для глаз (4), заземление (5), мышечная релаксация (7), доброе слово себе (8) и практики осознанности. Выбери любое."
+	b.send(chatID, response, &keyboard)
+	b.fire(chatID, dialog.OfferExercises)
+	return response
+}
+
+// stopCommand ends the conversation until the next greeting
+func (b *Bot) stopCommand(chatID int64, username string, args []string) string {
+	delete(b.pendingFeedback, chatID)
+	b.fire(chatID, dialog.Stop)
+
+	response := "Хорошо, закончим на этом. Напиши /start или «привет», когда захочешь поговорить. 👋"
+	b.send(chatID, response, nil)
+	return response
+}
+
+// helpCommand lists the commands
+func (b *Bot) helpCommand(chatID int64, username string, args []string) string {
+	response := "Я помогаю разобраться с настроением и предлагаю упражнения. " +
+		"Просто напиши или надиктуй голосовым, как ты себя чувствуешь.\n\n" + helpText()
+	b.send(chatID, response, nil)
+	return response
+}
+
+// helpText lists the commands with their arguments, one per line
+func helpText() string {
+	var lines []string
+	for _, c := range commandList() {
+		usage := "/" + c.name
+		if c.args != "" {
+			usage += " " + c.args
+		}
+		lines = append(lines, fmt.Sprintf("%s — %s", usage, c.description))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tg_bot/configs"
+	"tg_bot/internal/bot/bottest"
+)
+
+// newTestBot creates a bot on the in-memory API with its logs in a temporary directory
+func newTestBot(t *testing.T) (*Bot, *bottest.API, string) {
+	t.Helper()
+	logDir := t.TempDir()
+	api := bottest.NewAPI()
+	b, err := NewWithAPI(api, &configs.Config{LexiconDir: "../../configs/lexicon", LogDir: logDir, DebounceInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("Error creating bot: %v", err)
+	}
+	t.Cleanup(b.Close)
+	return b, api, logDir
+}
+
+func TestMoodCommandLogsOnce(t *testing.T) {
+	b, _, logDir := newTestBot(t)
+	b.HandleUpdate(bottest.TextMessage(1, "/mood мне грустно"))
+
+	data, err := os.ReadFile(filepath.Join(logDir, "bot.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) != 1 {
+		t.Fatalf("bot.log has %d entries, want 1:\n%s", len(lines), data)
+	}
+	var entry struct {
+		BotResponse string `json:"bot_response"`
+	}
+	if err := json.Unmarshal(lines[0], &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.BotResponse == "" {
+		t.Errorf("bot.log entry has no reply: %s", lines[0])
+	}
+}
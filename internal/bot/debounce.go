@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"strings"
+	"time"
+
+	"tg_bot/internal/dialog"
+	"tg_bot/internal/normalize"
+)
+
+// messageBatch collects the messages a user sends in quick succession
+type messageBatch struct {
+	username    string
+	messageType string
+	texts       []string
+	timer       *time.Timer
+}
+
+// debounce adds the message to the chat's batch and restarts the timer.
+// When no new message arrives within the interval, the chat ID is sent to
+// b.flushes and Run answers the whole batch once.
+func (b *Bot) debounce(chatID int64, username, messageType, text string) {
+	if b.debounceInterval <= 0 {
+		b.handleMessage(chatID, username, messageType, text)
+		return
+	}
+
+	batch := b.batches[chatID]
+	if batch == nil {
+		batch = &messageBatch{messageType: messageType}
+		b.batches[chatID] = batch
+	} else {
+		// Если таймер уже успел сработать, пачка уйдет чуть раньше вместе с этим
+		// сообщением, а лишний сигнал потом придет для пустой пачки и будет пропущен
+		batch.timer.Stop()
+	}
+	batch.username = username
+	// Стикеры вперемешку с текстом анализируем как текст
+	if batch.messageType != messageType {
+		batch.messageType = "text"
+	}
+	batch.texts = append(batch.texts, text)
+	batch.timer = time.AfterFunc(b.debounceInterval, func() {
+		// После Close сигнал читать некому
+		select {
+		case b.flushes <- chatID:
+		case <-b.done:
+		}
+	})
+}
+
+// flush answers the messages collected for the chat as one text, joined with
+// periods like the answers in withContext. A greeting in the batch is answered
+// first and the other messages after it: "привет" and "я очень устал" get both
+// the greeting and the mood reply.
+func (b *Bot) flush(chatID int64) {
+	batch := b.batches[chatID]
+	if batch == nil {
+		return
+	}
+	delete(b.batches, chatID)
+	batch.timer.Stop()
+
+	var greetings, rest []string
+	for _, text := range batch.texts {
+		if normalize.ContainsWord(text, "привет") {
+			greetings = append(greetings, text)
+		} else {
+			rest = append(rest, text)
+		}
+	}
+	if len(greetings) > 0 && len(rest) > 0 && b.dialog.Can(chatID, dialog.Greet) {
+		b.handleMessage(chatID, batch.username, batch.messageType, strings.Join(greetings, ". "))
+		b.handleMessage(chatID, batch.username, batch.messageType, strings.Join(rest, ". "))
+		return
+	}
+	b.handleMessage(chatID, batch.username, batch.messageType, strings.Join(batch.texts, ". "))
+}
+
+// FlushPending answers the collected messages of every chat without waiting
+// for the debounce timers
+func (b *Bot) FlushPending() {
+	for chatID := range b.batches {
+		b.flush(chatID)
+	}
+}
+
+// stopBatches stops the timers of the batches that were not answered
+func (b *Bot) stopBatches() {
+	for _, batch := range b.batches {
+		batch.timer.Stop()
+	}
+}
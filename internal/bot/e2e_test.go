@@ -0,0 +1,103 @@
+package bot_test
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tg_bot/configs"
+	"tg_bot/internal/bot"
+	"tg_bot/internal/bot/bottest"
+	"tg_bot/internal/dialog"
+)
+
+// fakeTranscriber recognizes every voice message as the same text
+type fakeTranscriber string
+
+func (t fakeTranscriber) Transcribe(oggPath string) (string, error) {
+	return string(t), nil
+}
+
+// TestRunEndToEnd runs the bot against the fake Bot API over HTTP, the same
+// way cmd/bot talks to Telegram: long polling, sent messages, a voice download
+// and a button press
+func TestRunEndToEnd(t *testing.T) {
+	lexiconDir, err := filepath.Abs("../../configs/lexicon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Голосовые скачиваются в temp/ текущего каталога
+	t.Chdir(t.TempDir())
+
+	const token = "123:test"
+	const chatID = 7
+	server := bottest.NewServer(token)
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	// Прерываем длинный опрос, иначе Close ждет его окончания
+	t.Cleanup(httpServer.CloseClientConnections)
+
+	api, err := bot.NewAPI(token, httpServer.URL)
+	if err != nil {
+		t.Fatalf("Error connecting to fake Bot API: %v", err)
+	}
+	b, err := bot.NewWithAPI(api, &configs.Config{
+		LexiconDir:       lexiconDir,
+		LogDir:           t.TempDir(),
+		DebounceInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Error creating bot: %v", err)
+	}
+	b.SetTranscriber(fakeTranscriber("я очень устал"))
+
+	done := make(chan error, 1)
+	go func() { done <- b.Run() }()
+
+	wait := func(count int) []bottest.Message {
+		t.Helper()
+		messages, err := server.WaitMessages(chatID, count, 5*time.Second)
+		if err != nil {
+			t.Fatalf("Error waiting for replies: %v, got %+v", err, messages)
+		}
+		return messages
+	}
+
+	server.Inject(bottest.TextMessage(chatID, "привет"))
+	messages := wait(2)
+	if messages[0].Text != "Привет! 👋" || messages[1].Text != "Как ты сейчас?" {
+		t.Fatalf("Greeting = %q, %q", messages[0].Text, messages[1].Text)
+	}
+
+	server.AddFile("voice1", "voice/voice1.oga", []byte("OggS"))
+	server.Inject(bottest.Voice(chatID, "voice1"))
+	messages = wait(4)
+	if !strings.HasPrefix(messages[2].Text, "Похоже, ты совсем вымотан") {
+		t.Fatalf("Reply to the voice message = %q", messages[2].Text)
+	}
+	feedback := messages[3]
+	if len(feedback.Keyboard) == 0 || feedback.Keyboard[0][0].CallbackData == nil {
+		t.Fatalf("Feedback question %q has no buttons", feedback.Text)
+	}
+
+	server.Inject(bottest.Press(chatID, feedback.ID, *feedback.Keyboard[0][0].CallbackData))
+	messages = wait(5)
+	if messages[4].Text != "Спасибо! 🙏" {
+		t.Fatalf("Reply to the confirmation = %q", messages[4].Text)
+	}
+	if got := b.State(chatID); got != dialog.WaitingForExercise {
+		t.Errorf("State = %s, want waiting_for_exercise", got)
+	}
+
+	b.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after Stop")
+	}
+}
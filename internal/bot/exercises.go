@@ -0,0 +1,198 @@
+package bot
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"tg_bot/internal/dialog"
+	"tg_bot/internal/normalize"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// skipExercise is the callback data of the "Пропустить" button
+const skipExercise = "skip_exercise"
+
+// exercises are the texts of the exercises and practices by their callback data
+var exercises = map[string]string{
+	"exercise1": "Упражнение 1: Глубокое дыхание\n\n" +
+		"1. Сядьте удобно и расслабьтесь\n" +
+		"2. Сделайте глубокий вдох через нос на 4 счета\n" +
+		"3. Задержите дыхание на 4 счета\n" +
+		"4. Медленно выдохните через рот на 4 счета\n" +
+		"5. Повторите 5-7 раз\n\n" +
+		"Это упражнение поможет снять напряжение и восстановить энергию.",
+	"exercise2": "Упражнение 2: Растяжка шеи\n\n" +
+		"1. Сядьте прямо\n" +
+		"2. Медленно наклоните голову вправо, задержитесь на 10 секунд\n" +
+		"3. Вернитесь в исходное положение\n" +
+		"4. Повторите влево\n" +
+		"5. Сделайте по 3-4 раза в каждую сторону\n\n" +
+		"Это упражнение поможет снять напряжение в шее и плечах.",
+	"exercise3": "Упражнение 3: Мини-прогулка\n\n" +
+		"1. Встаньте и пройдитесь по комнате 2-3 минуты\n" +
+		"2. Делайте это в спокойном темпе\n" +
+		"3. Следите за дыханием\n" +
+		"4. Можно выйти на свежий воздух, если есть возможность\n\n" +
+		"Это упражнение поможет разогнать кровь и взбодриться.",
+	"exercise4": "Упражнение 4: Гимнастика для глаз\n\n" +
+		"1. Закройте глаза на 10 секунд\n" +
+		"2. Откройте и посмотрите вдаль 10 секунд\n" +
+		"3. Сделайте круговые движения глазами по часовой стрелке\n" +
+		"4. Повторите против часовой стрелки\n" +
+		"5. Сделайте 3-4 подхода\n\n" +
+		"Это упражнение поможет снять напряжение с глаз и улучшить концентрацию.",
+	"exercise5": "Упражнение 5: Заземление 5-4-3-2-1\n\n" +
+		"1. Назовите 5 вещей, которые вы видите\n" +
+		"2. Назовите 4 вещи, которые можете потрогать\n" +
+		"3. Назовите 3 звука, которые слышите\n" +
+		"4. Назовите 2 запаха\n" +
+		"5. Назовите 1 вкус\n\n" +
+		"Это упражнение поможет переключиться с тревожных мыслей на то, что происходит здесь и сейчас.",
+	"exercise6": "Упражнение 6: Дыхание с долгим выдохом\n\n" +
+		"1. Сделайте вдох через нос на 4 счета\n" +
+		"2. Задержите дыхание на 7 счетов\n" +
+		"3. Медленно выдохните через рот на 8 счетов\n" +
+		"4. Повторите 4 раза\n\n" +
+		"Длинный выдох замедляет пульс и помогает остыть, прежде чем что-то говорить или делать.",
+	"exercise7": "Упражнение 7: Мышечная релаксация\n\n" +
+		"1. Сядьте удобно и закройте глаза\n" +
+		"2. Сильно сожмите кулаки на 5 секунд, затем расслабьте\n" +
+		"3. Поднимите плечи к ушам на 5 секунд, затем опустите\n" +
+		"4. Напрягите и расслабьте мышцы лица, живота и ног\n" +
+		"5. Обратите внимание на разницу между напряжением и расслаблением\n\n" +
+		"Это упражнение поможет сбросить накопившееся за день напряжение.",
+	"exercise8": "Упражнение 8: Доброе слово себе\n\n" +
+		"1. Положите руку на сердце\n" +
+		"2. Признайте: \"Мне сейчас грустно, и это нормально\"\n" +
+		"3. Подумайте, что бы вы сказали близкому другу в такой ситуации\n" +
+		"4. Скажите эти слова себе\n" +
+		"5. Сделайте одну маленькую приятную вещь: чай, музыка, плед\n\n" +
+		"Это упражнение поможет отнестись к себе бережно, когда на душе тяжело.",
+	"mindfulness1": "Практика 'Вижу, слышу, чувствую' 🌟\n\n" +
+		"Это простая и мощная техника осознанности (mindfulness), которая помогает вернуться в настоящий момент, заземлиться, снизить тревожность и выйти из потока мыслей.\n\n" +
+		"Как выполнять:\n" +
+		"1. Сядьте или встаньте спокойно\n" +
+		"2. Ненадолго остановитесь\n" +
+		"3. Начните замечать то, что происходит прямо сейчас\n\n" +
+		"Не нужно ничего анализировать, оценивать или 'делать правильно'. Только наблюдать и отмечать словами:\n\n" +
+		"Вижу...\n" +
+		"Слышу...\n" +
+		"Чувствую...\n\n" +
+		"Эта практика особенно полезна в повседневной жизни, когда хочется остановиться и просто быть.",
+	"mindfulness2": "Практика 'ABC noting' 🌟\n\n" +
+		"Это простая, но мощная техника осознанности (mindfulness), которая помогает заметить, в какой 'зоне' ты находишься прямо сейчас, и перевести внимание из автоматического реагирования в осознанность.\n\n" +
+		"Как выполнять:\n\n" +
+		"A — Aware (осознаю):\n" +
+		"Заметь, что происходит прямо сейчас.\n\n" +
+		"B — Balance:\n" +
+		"Найди равновесие в своей позе.\n\n" +
+		"C - Concentrate:\n" +
+		"Вытянись вверх и расслабь лицо, шею, грудную клетку, диафрагму и живот. Оглянись и заметь себя и место, где ты находишься.",
+}
+
+// exerciseNames are the words a user can type instead of pressing a button,
+// as word beginnings: "дыхание" and "дыхательное" both start with "дыха".
+// Номер упражнения тоже подходит: "1" — это exercise1.
+var exerciseNames = []struct {
+	data  string
+	words []string
+}{
+	{"exercise1", []string{"дыха", "вдох"}},
+	{"exercise2", []string{"растяж", "шея", "шеи", "шею"}},
+	{"exercise3", []string{"прогул", "погуля", "пройти", "пройдус"}},
+	{"exercise4", []string{"глаз", "гимнаст"}},
+	{"exercise5", []string{"заземл"}},
+	{"exercise6", []string{"выдох"}},
+	{"exercise7", []string{"релакс", "мышц"}},
+	{"exercise8", []string{"добро"}},
+	{"mindfulness1", []string{"вижу", "слышу"}},
+	{"mindfulness2", []string{"abc", "нотинг"}},
+}
+
+// skipWords are the words that decline the exercises. They are compared as
+// whole words: "потом" is not the beginning of "потому" or "потолок".
+var skipWords = []string{"нет", "потом", "позже", "неохота", "хватит", "skip"}
+
+// skipStems are the word beginnings that decline the exercises
+var skipStems = []string{"пропуст"}
+
+// skipPhrases decline the exercises with a negated verb
+var skipPhrases = []string{"не хочу", "не сейчас", "не надо", "не буду"}
+
+// skipReply answers a skipped exercise
+const skipReply = "Хорошо, в другой раз. 🙂 Если захочешь поговорить, просто напиши мне."
+
+// exerciseHint explains how to choose when the answer is not an exercise
+const exerciseHint = "Выбери упражнение кнопкой или напиши его номер или название, например «1» или «дыхание». Если не хочется, напиши «пропустить»."
+
+// skipRow is the button that declines the offered exercises
+func skipRow() []tgbotapi.InlineKeyboardButton {
+	return tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Пропустить", skipExercise),
+	)
+}
+
+// findExercise returns the callback data of the exercise named in a typed
+// answer, or "" if there is none
+func findExercise(text string) string {
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if n, err := strconv.Atoi(field); err == nil {
+			if data := "exercise" + strconv.Itoa(n); exercises[data] != "" {
+				return data
+			}
+		}
+	}
+	for _, e := range exerciseNames {
+		for _, word := range e.words {
+			if normalize.ContainsWord(text, normalize.Word(word)) {
+				return e.data
+			}
+		}
+	}
+	return ""
+}
+
+// isSkip reports whether a typed answer declines the exercises
+func isSkip(text string) bool {
+	normalized := normalize.Text(text)
+	for _, phrase := range skipPhrases {
+		if strings.Contains(normalized, phrase) {
+			return true
+		}
+	}
+	for _, word := range skipWords {
+		if normalize.HasWord(text, normalize.Word(word)) {
+			return true
+		}
+	}
+	for _, stem := range skipStems {
+		if normalize.ContainsWord(text, normalize.Word(stem)) {
+			return true
+		}
+	}
+	return false
+}
+
+// chooseExercise answers a typed message while the bot waits for an exercise
+// choice: the named exercise, a skip, or a hint how to choose
+func (b *Bot) chooseExercise(chatID int64, username, messageType, text string) {
+	var response string
+	if data := findExercise(text); data != "" {
+		response = exercises[data]
+		b.fire(chatID, dialog.ChooseExercise)
+	} else if isSkip(text) {
+		response = skipReply
+		b.fire(chatID, dialog.Skip)
+	} else {
+		// Остаемся в ожидании: тайм-аут все равно вернет пользователя в начало
+		response = exerciseHint
+	}
+	b.send(chatID, response, nil)
+
+	if err := b.logger.Log(chatID, username, messageType, text, response); err != nil {
+		log.Printf("Error logging exercise choice: %v", err)
+	}
+}
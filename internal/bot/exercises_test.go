@@ -0,0 +1,23 @@
+package bot
+
+import "testing"
+
+func TestIsSkip(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"потом", true},
+		{"давай позже", true},
+		{"пропусти", true},
+		{"не сейчас", true},
+		{"потому что устал", false},
+		{"смотрю в потолок", false},
+		{"давай 3", false},
+	}
+	for _, tt := range tests {
+		if got := isSkip(tt.text); got != tt.want {
+			t.Errorf("isSkip(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"log"
+	"strings"
+
+	"tg_bot/internal/mood"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// feedbackPrefix starts the callback data of the "did I get that right?" buttons
+const feedbackPrefix = "feedback:"
+
+// feedbackConfirm is the callback choice of the "yes" button
+const feedbackConfirm = "ok"
+
+// feedbackOptions are the moods a user can pick to correct the bot, in button order
+var feedbackOptions = []struct {
+	label string
+	mood  string
+}{
+	{"Устал", "tired"},
+	{"Грустно", "sadness"},
+	{"Хорошо", "positive"},
+	{"Бодро", "energized"},
+}
+
+// pendingFeedback is the last classified message of a chat, kept until the user
+// confirms or corrects the detected mood
+type pendingFeedback struct {
+	text        string
+	messageType string
+	detected    string
+	// messageID is the question with the buttons; presses under older
+	// questions do not answer this one
+	messageID int
+}
+
+// isFeedbackChoice reports whether the callback choice is one of the feedback buttons
+func isFeedbackChoice(choice string) bool {
+	if choice == feedbackConfirm {
+		return true
+	}
+	for _, o := range feedbackOptions {
+		if o.mood == choice {
+			return true
+		}
+	}
+	return false
+}
+
+func feedbackKeyboard() tgbotapi.InlineKeyboardMarkup {
+	var corrections []tgbotapi.InlineKeyboardButton
+	for _, o := range feedbackOptions {
+		corrections = append(corrections, tgbotapi.NewInlineKeyboardButtonData(o.label, feedbackPrefix+o.mood))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("👍 Да, верно", feedbackPrefix+feedbackConfirm),
+		),
+		corrections,
+	)
+}
+
+// askFeedback asks the user whether the detected mood was right and remembers
+// the message, so the answer can be stored as a labeled example
+func (b *Bot) askFeedback(chatID int64, text, messageType, detected string) {
+	delete(b.pendingFeedback, chatID)
+
+	msg := tgbotapi.NewMessage(chatID, "Я правильно понял твое настроение? Если нет, выбери, как ты себя чувствуешь.")
+	msg.ReplyMarkup = feedbackKeyboard()
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		log.Printf("Error sending feedback question: %v", err)
+		return
+	}
+	b.pendingFeedback[chatID] = pendingFeedback{text: text, messageType: messageType, detected: detected, messageID: sent.MessageID}
+}
+
+// handleFeedback stores the confirmed or corrected mood next to the original text
+// and, after a correction, answers as if the corrected mood had been detected
+func (b *Bot) handleFeedback(callback *tgbotapi.CallbackQuery, username string) {
+	chatID := callback.Message.Chat.ID
+	choice := strings.TrimPrefix(callback.Data, feedbackPrefix)
+
+	// Данные кнопки приходят от клиента: в размеченные примеры пускаем только свои варианты
+	if !isFeedbackChoice(choice) {
+		log.Printf("Unknown feedback choice %q from user %d", choice, chatID)
+		b.answerCallback(callback.ID, "")
+		return
+	}
+
+	// Убираем кнопки, чтобы ответ нельзя было выбрать дважды
+	removeButtons := tgbotapi.NewEditMessageReplyMarkup(chatID, callback.Message.MessageID,
+		tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+	if _, err := b.api.Request(removeButtons); err != nil {
+		log.Printf("Error removing feedback buttons: %v", err)
+	}
+
+	// Кнопки под старым вопросом относятся к другому сообщению, их ответ не сохраняем
+	pending, ok := b.pendingFeedback[chatID]
+	if !ok || pending.messageID != callback.Message.MessageID {
+		b.answerCallback(callback.ID, "Спасибо!")
+		return
+	}
+	delete(b.pendingFeedback, chatID)
+
+	label := pending.detected
+	if label == "neutral_final" {
+		label = mood.Neutral
+	}
+	confirmed := choice == feedbackConfirm || choice == label
+	if !confirmed {
+		label = choice
+	}
+
+	if err := b.feedbackLogger.LogFeedback(chatID, pending.messageType, pending.text, pending.detected, label, confirmed); err != nil {
+		log.Printf("Error logging feedback: %v", err)
+	}
+
+	var response string
+	if confirmed {
+		response = "Спасибо! 🙏"
+		b.send(chatID, response, nil)
+	} else {
+		// Переходим в ветку исправленного настроения, как будто бот сразу понял верно
+		reply, keyboard, event := moodResponse(label, mood.Moderate)
+		response = "Понял, спасибо, что поправил! " + reply
+		b.send(chatID, response, keyboard)
+		b.fire(chatID, event)
+	}
+
+	if err := b.logger.Log(chatID, username, "callback", callback.Data, response, label); err != nil {
+		log.Printf("Error logging feedback callback: %v", err)
+	}
+	b.answerCallback(callback.ID, "")
+}
+
+// send sends a text message with optional inline buttons
+func (b *Bot) send(chatID int64, text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	if _, err := b.api.Send(msg); err != nil {
+		log.Printf("Error sending message: %v", err)
+	}
+}
+
+// answerCallback removes the loading state of a pressed button
+func (b *Bot) answerCallback(callbackID, text string) {
+	if _, err := b.api.Request(tgbotapi.NewCallback(callbackID, text)); err != nil {
+		log.Printf("Error answering callback query: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tg_bot/internal/bot/bottest"
+)
+
+func TestFeedbackRejectsUnknownChoice(t *testing.T) {
+	b, api, logDir := newTestBot(t)
+
+	const chatID = 1
+	b.HandleUpdate(bottest.TextMessage(chatID, "привет"))
+	b.HandleUpdate(bottest.TextMessage(chatID, "мне грустно"))
+	b.FlushPending()
+	messages := api.ChatMessages(chatID)
+	question := messages[len(messages)-1]
+
+	b.HandleUpdate(bottest.Press(chatID, question.ID, feedbackPrefix+"bogus"))
+	if got := len(api.ChatMessages(chatID)); got != len(messages) {
+		t.Errorf("Bot sent %d messages after an unknown choice", got-len(messages))
+	}
+	if _, ok := b.pendingFeedback[chatID]; !ok {
+		t.Error("Unknown choice answered the pending question")
+	}
+	data, err := os.ReadFile(filepath.Join(logDir, "feedback.jsonl"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if len(data) > 0 {
+		t.Errorf("Unknown choice logged as feedback: %s", data)
+	}
+}
@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tg_bot/internal/dialog"
+	"tg_bot/internal/mood"
+	"tg_bot/internal/normalize"
+	"tg_bot/internal/speech"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Every message goes through one pipeline:
+//
+//	voice → transcribeVoice ┐
+//	sticker → its emoji     ├→ debounce → handleMessage → greet | chooseExercise | answerMood
+//	text ───────────────────┘
+//
+// so voice, text and stickers get the same replies, dialog states and log entries.
+// The mood is analyzed only while the bot waits for it, after a greeting or /mood.
+
+// maxMoodAttempts is how many unclear answers the bot asks about before it gives up
+const maxMoodAttempts = 3
+
+// transcribeVoice downloads the voice message and turns it into lower-case text
+func (b *Bot) transcribeVoice(voice *tgbotapi.Voice) (string, error) {
+	// Download the voice message
+	link, err := b.api.GetFileDirectURL(voice.FileID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get file: %v", err)
+	}
+	log.Printf("Got file link for %s", voice.FileID)
+
+	// Create temp directory if it doesn't exist
+	if err := os.MkdirAll("temp", 0755); err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %v", err)
+	}
+
+	// Download the file
+	resp, err := http.Get(link)
+	if err != nil {
+		return "", fmt.Errorf("failed to download file: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download file: %s", resp.Status)
+	}
+	log.Printf("Downloaded file successfully")
+
+	// Save the file
+	audioPath := filepath.Join("temp", fmt.Sprintf("%s.ogg", voice.FileID))
+	out, err := os.Create(audioPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %v", err)
+	}
+	defer speech.CleanupAudioFiles(audioPath)
+	defer out.Close()
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to save file: %v", err)
+	}
+	log.Printf("Saved file to %s", audioPath)
+
+	// Log file info
+	fileInfo, err := os.Stat(audioPath)
+	if err != nil {
+		log.Printf("Error getting file info: %v", err)
+	} else {
+		log.Printf("Audio file size: %d bytes", fileInfo.Size())
+	}
+
+	// Transcribe the audio
+	text, err := b.transcriber.Transcribe(audioPath)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Transcribed text: %s", text)
+
+	return strings.ToLower(text), nil
+}
+
+// handleMessage answers a text message, a sticker, a transcribed voice message
+// or several of them sent in quick succession and merged by debounce
+func (b *Bot) handleMessage(chatID int64, username, messageType, text string) {
+	state := b.dialog.State(chatID)
+
+	switch {
+	case normalize.ContainsWord(text, "привет") && b.dialog.Can(chatID, dialog.Greet):
+		// Разговор начинается заново: старый вопрос о настроении больше не ждет ответа
+		delete(b.pendingFeedback, chatID)
+		response := b.greet(chatID)
+		b.fire(chatID, dialog.Greet)
+
+		// Логируем приветствие
+		if err := b.logger.Log(chatID, username, messageType, text, response); err != nil {
+			log.Printf("Error logging greeting: %v", err)
+		}
+
+	case state == dialog.WaitingForExercise:
+		b.chooseExercise(chatID, username, messageType, text)
+
+	case state == dialog.WaitingForMood:
+		b.answerMood(chatID, username, messageType, text)
+
+	default:
+		// Вне диалога (после /stop, тайм-аута или ответа) настроение не разбираем
+		log.Printf("Ignoring %s message from user %d in state %s", messageType, chatID, state)
+	}
+}
+
+// answerMood classifies the message together with the recent answers and
+// replies to the mood as described in design/mood_responses.md
+func (b *Bot) answerMood(chatID int64, username, messageType, text string) {
+	// Несколько коротких ответов вместе могут дать ясный сигнал
+	moodText, earlier := b.withContext(chatID, text)
+	result := b.analyzeMood(chatID, moodText)
+	detected := result.Mood
+	log.Printf("Detected mood: %s (%s)", detected, result.Intensity)
+
+	// Если после 3 попыток не удалось определить настроение, считаем его нейтральным
+	b.moodAttempts[chatID]++
+	if detected == mood.Neutral && b.moodAttempts[chatID] >= maxMoodAttempts {
+		detected = "neutral_final"
+	}
+
+	var response string
+	var event dialog.Event
+	moods := []string{detected}
+	if result.Mixed() {
+		// Несколько состояний сразу: отвечаем на все и объединяем упражнения
+		response = b.sendCombined(chatID, result)
+		event = mixedEvent(result)
+		moods = moodNames(result)
+	} else {
+		var keyboard *tgbotapi.InlineKeyboardMarkup
+		response, keyboard, event = moodResponse(detected, result.Intensity)
+		b.send(chatID, response, keyboard)
+	}
+	b.fire(chatID, event)
+
+	if err := b.logger.LogMood(chatID, username, messageType, text, response, earlier, result, moods...); err != nil {
+		log.Printf("Error logging %s message: %v", messageType, err)
+	}
+
+	// Пока бот просит рассказать побольше, спрашивать о правильности рано
+	if event != dialog.AskMore {
+		b.askFeedback(chatID, moodText, messageType, moods[0])
+	}
+}
+
+// moodResponse returns the reply to a mood, its exercise buttons if any and the
+// dialog event that follows. It serves every message type and mood corrections.
+func moodResponse(detected string, intensity mood.Intensity) (string, *tgbotapi.InlineKeyboardMarkup, dialog.Event) {
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	switch detected {
+	case "energized":
+		return "Отлично! 💪 Такая энергия - это здорово! Держи этот настрой и используй его для достижения своих целей!", nil, dialog.Answer
+	case "tired":
+		var response string
+		response, keyboard = tiredReply(intensity)
+		return response, &keyboard, dialog.OfferExercises
+	case "positive":
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(exerciseRows("positive", intensity)...)
+		return "Рад слышать, что у тебя всё хорошо! 😊 Давай сохраним это настроение! Предлагаю сделать практику осознанности.", &keyboard, dialog.Answer
+	case "negative":
+		// После упражнений от негатива бот ждет выбора, поэтому от них можно отказаться
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(append(exerciseRows("negative", intensity), skipRow())...)
+		return "Мне жаль, что тебе сейчас нелегко. Давай я предложу тебе несколько упражнений, которые помогут улучшить настроение.", &keyboard, dialog.OfferExercises
+	case "anxiety", "anger", "sadness", "stress", "calm":
+		var response string
+		response, keyboard = categoryReply(detected, intensity)
+		return response, &keyboard, dialog.Answer
+	case "neutral_final":
+		return "Спасибо за ответ! Надеюсь, у тебя будет хороший день! 🌞", nil, dialog.Answer
+	default:
+		return "Расскажи мне побольше.", nil, dialog.AskMore
+	}
+}
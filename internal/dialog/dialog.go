@@ -0,0 +1,180 @@
+// Package dialog is the conversation state machine of the bot: the states a chat
+// can be in, the events that move it between them, entry actions and timeouts.
+// It knows nothing about Telegram, so a dialog can be driven by plain calls.
+package dialog
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// State is where a chat is in the conversation
+type State string
+
+const (
+	// Idle waits for a greeting; a chat without a state is idle
+	Idle State = "idle"
+	// WaitingForMood has asked "Как ты сейчас?" or "Расскажи мне побольше."
+	WaitingForMood State = "waiting_for_mood"
+	// WaitingForExercise has offered exercises and waits for a choice
+	WaitingForExercise State = "waiting_for_exercise"
+)
+
+// Event is something the bot did or the user said that can change the state
+type Event string
+
+const (
+	// Greet: the user said hello and the bot asked how they are
+	Greet Event = "greet"
+	// AskMore: the mood is unclear and the bot asked for more details
+	AskMore Event = "ask_more"
+	// Answer: the bot replied to the mood and waits for nothing
+	Answer Event = "answer"
+	// OfferExercises: the bot replied with exercises to choose from
+	OfferExercises Event = "offer_exercises"
+	// ChooseExercise: the user picked an exercise
+	ChooseExercise Event = "choose_exercise"
+	// Skip: the user declined the exercises
+	Skip Event = "skip"
+	// Stop: the user ended the conversation with /stop, or it starts over
+	Stop Event = "stop"
+	// Timeout: the chat stayed in a state longer than its timeout
+	Timeout Event = "timeout"
+)
+
+// Transition moves a chat to To when Event happens in one of the From states.
+// Empty From means any state.
+type Transition struct {
+	Event Event
+	From  []State
+	To    State
+}
+
+// Transitions is the dialog of the bot, see design/mood_responses.md
+var Transitions = []Transition{
+	{Event: Greet, From: []State{Idle, WaitingForExercise}, To: WaitingForMood},
+	{Event: AskMore, To: WaitingForMood},
+	{Event: Answer, To: Idle},
+	{Event: OfferExercises, To: WaitingForExercise},
+	{Event: ChooseExercise, From: []State{WaitingForExercise}, To: Idle},
+	{Event: Skip, From: []State{WaitingForExercise}, To: Idle},
+	{Event: Stop, To: Idle},
+	{Event: Timeout, From: []State{WaitingForMood, WaitingForExercise}, To: Idle},
+}
+
+// Timeouts is how long a chat may stay silent in a state before it times out
+var Timeouts = map[State]time.Duration{
+	WaitingForMood:     30 * time.Minute,
+	WaitingForExercise: time.Hour,
+}
+
+// allStates are the states a transition with empty From starts in
+var allStates = []State{Idle, WaitingForMood, WaitingForExercise}
+
+// Machine keeps the state of every chat. It is not safe for concurrent use:
+// the bot drives it from its update loop.
+type Machine struct {
+	next     map[State]map[Event]State
+	timeouts map[State]time.Duration
+	onEnter  map[State][]func(chatID int64)
+	chats    map[int64]chat
+	now      func() time.Time
+}
+
+// chat is the state of one chat and the time of its last transition
+type chat struct {
+	state State
+	since time.Time
+}
+
+// New builds a machine from the transitions and timeouts. A state and an event
+// may have only one transition.
+func New(transitions []Transition, timeouts map[State]time.Duration) (*Machine, error) {
+	m := &Machine{
+		next:     make(map[State]map[Event]State),
+		timeouts: timeouts,
+		onEnter:  make(map[State][]func(chatID int64)),
+		chats:    make(map[int64]chat),
+		now:      time.Now,
+	}
+	for _, t := range transitions {
+		from := t.From
+		if len(from) == 0 {
+			from = allStates
+		}
+		for _, s := range from {
+			if m.next[s] == nil {
+				m.next[s] = make(map[Event]State)
+			}
+			if to, ok := m.next[s][t.Event]; ok {
+				return nil, fmt.Errorf("duplicate transition %s --%s--> %s and %s", s, t.Event, to, t.To)
+			}
+			m.next[s][t.Event] = t.To
+		}
+	}
+	for s := range timeouts {
+		if _, ok := m.next[s][Timeout]; !ok {
+			return nil, fmt.Errorf("state %s has a timeout but no %s transition", s, Timeout)
+		}
+	}
+	return m, nil
+}
+
+// OnEnter adds an action that runs every time a chat enters the state,
+// including a transition from the state to itself
+func (m *Machine) OnEnter(state State, action func(chatID int64)) {
+	m.onEnter[state] = append(m.onEnter[state], action)
+}
+
+// SetClock replaces time.Now, so timeouts can be checked without waiting
+func (m *Machine) SetClock(now func() time.Time) {
+	m.now = now
+}
+
+// State returns the state of the chat. A chat that stayed in a state longer
+// than its timeout is moved along its Timeout transition first.
+func (m *Machine) State(chatID int64) State {
+	c, ok := m.chats[chatID]
+	if !ok {
+		return Idle
+	}
+	if timeout, ok := m.timeouts[c.state]; ok && m.now().Sub(c.since) >= timeout {
+		return m.enter(chatID, c.state, Timeout)
+	}
+	return c.state
+}
+
+// Can reports whether the event has a transition from the current state of the chat
+func (m *Machine) Can(chatID int64, event Event) bool {
+	_, ok := m.next[m.State(chatID)][event]
+	return ok
+}
+
+// Fire moves the chat along the transition of the event and runs the entry
+// actions of the new state. Without a transition the state is left as is.
+func (m *Machine) Fire(chatID int64, event Event) (State, error) {
+	from := m.State(chatID)
+	if _, ok := m.next[from][event]; !ok {
+		return from, fmt.Errorf("no transition for %s in state %s", event, from)
+	}
+	return m.enter(chatID, from, event), nil
+}
+
+// enter takes the transition of the event from the state, which must exist
+func (m *Machine) enter(chatID int64, from State, event Event) State {
+	to := m.next[from][event]
+	if to == Idle {
+		// Простаивающие чаты не храним
+		delete(m.chats, chatID)
+	} else {
+		m.chats[chatID] = chat{state: to, since: m.now()}
+	}
+	if to != from {
+		log.Printf("Dialog %d: %s --%s--> %s", chatID, from, event, to)
+	}
+	for _, action := range m.onEnter[to] {
+		action(chatID)
+	}
+	return to
+}
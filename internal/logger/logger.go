@@ -6,16 +6,79 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"tg_bot/internal/mood"
 )
 
 type LogEntry struct {
 	Timestamp   string `json:"timestamp"`
 	UserID      int64  `json:"user_id"`
 	Username    string `json:"username"`
-	MessageType string `json:"message_type"` // "voice" или "text"
+	MessageType string `json:"message_type"` // "voice", "text", "sticker" или "callback"
 	Content     string `json:"content"`
-	BotResponse string `json:"bot_response"`
-	Mood        string `json:"mood,omitempty"`
+	// Context — предыдущие ответы, которые анализировались вместе с сообщением
+	Context     []string `json:"context,omitempty"`
+	BotResponse string   `json:"bot_response"`
+	Mood        string   `json:"mood,omitempty"`
+	// ParentMood — широкое настроение для тревоги, злости, грусти, стресса и спокойствия.
+	// Старые записи с "negative" и "positive" читаются как эти же родительские категории.
+	ParentMood string `json:"parent_mood,omitempty"`
+	// Moods перечисляет все состояния, если пользователь назвал несколько сразу
+	Moods []string `json:"moods,omitempty"`
+	// Confidence и Trace объясняют, почему выбрано настроение: какие слова
+	// совпали со словарем, в какой категории и с каким весом
+	Confidence float64      `json:"confidence,omitempty"`
+	Trace      []mood.Match `json:"trace,omitempty"`
+}
+
+// ShadowEntry сравнивает ответ основного классификатора с кандидатом в теневом режиме
+type ShadowEntry struct {
+	Timestamp string        `json:"timestamp"`
+	UserID    int64         `json:"user_id"`
+	Content   string        `json:"content"`
+	Primary   ShadowVerdict `json:"primary"`
+	Shadow    ShadowVerdict `json:"shadow"`
+}
+
+// ShadowVerdict — результат одного классификатора
+type ShadowVerdict struct {
+	Classifier string       `json:"classifier"`
+	Mood       string       `json:"mood"`
+	Moods      []string     `json:"moods,omitempty"`
+	Confidence float64      `json:"confidence"`
+	Trace      []mood.Match `json:"trace,omitempty"`
+}
+
+// NewShadowVerdict собирает запись о результате классификатора
+func NewShadowVerdict(classifier string, result mood.Result) ShadowVerdict {
+	v := ShadowVerdict{
+		Classifier: classifier,
+		Mood:       result.Mood,
+		Confidence: result.Confidence,
+		Trace:      result.Matches,
+	}
+	if result.Mixed() {
+		for _, m := range result.Moods {
+			v.Moods = append(v.Moods, m.Mood)
+		}
+	}
+	return v
+}
+
+// FeedbackEntry — настроение, подтвержденное или исправленное пользователем.
+// Поля text и mood совпадают с форматом корпусов, поэтому файл можно сразу
+// передать в moodtrain -data и moodeval -corpus.
+type FeedbackEntry struct {
+	Timestamp   string `json:"timestamp"`
+	UserID      int64  `json:"user_id"`
+	MessageType string `json:"message_type"`
+	// Text — исходный текст или расшифровка голосового
+	Text string `json:"text"`
+	// Mood — настроение по словам пользователя
+	Mood string `json:"mood"`
+	// Detected — что определил бот
+	Detected  string `json:"detected"`
+	Confirmed bool   `json:"confirmed"`
 }
 
 type Logger struct {
@@ -39,7 +102,46 @@ func New(logPath string) (*Logger, error) {
 	}, nil
 }
 
-func (l *Logger) Log(userID int64, username, messageType, content, botResponse, mood string) error {
+// Log записывает сообщение и ответ бота. Первое из moods считается основным настроением.
+func (l *Logger) Log(userID int64, username, messageType, content, botResponse string, moods ...string) error {
+	return l.write(newEntry(userID, username, messageType, content, botResponse, moods))
+}
+
+// LogMood записывает сообщение вместе с объяснением результата анализа настроения.
+// context — предыдущие ответы, проанализированные вместе с сообщением.
+func (l *Logger) LogMood(userID int64, username, messageType, content, botResponse string, context []string, result mood.Result, moods ...string) error {
+	entry := newEntry(userID, username, messageType, content, botResponse, moods)
+	entry.Context = context
+	entry.Confidence = result.Confidence
+	entry.Trace = result.Matches
+	return l.write(entry)
+}
+
+// LogShadow записывает расхождение основного классификатора с теневым
+func (l *Logger) LogShadow(userID int64, content string, primary, shadow ShadowVerdict) error {
+	return l.write(ShadowEntry{
+		Timestamp: time.Now().Format(time.RFC3339),
+		UserID:    userID,
+		Content:   content,
+		Primary:   primary,
+		Shadow:    shadow,
+	})
+}
+
+// LogFeedback записывает ответ пользователя на вопрос "правильно ли я понял настроение?"
+func (l *Logger) LogFeedback(userID int64, messageType, text, detected, label string, confirmed bool) error {
+	return l.write(FeedbackEntry{
+		Timestamp:   time.Now().Format(time.RFC3339),
+		UserID:      userID,
+		MessageType: messageType,
+		Text:        text,
+		Mood:        label,
+		Detected:    detected,
+		Confirmed:   confirmed,
+	})
+}
+
+func newEntry(userID int64, username, messageType, content, botResponse string, moods []string) LogEntry {
 	entry := LogEntry{
 		Timestamp:   time.Now().Format(time.RFC3339),
 		UserID:      userID,
@@ -47,9 +149,20 @@ func (l *Logger) Log(userID int64, username, messageType, content, botResponse,
 		MessageType: messageType,
 		Content:     content,
 		BotResponse: botResponse,
-		Mood:        mood,
 	}
+	if len(moods) > 0 {
+		entry.Mood = moods[0]
+		if parent := mood.Parent(moods[0]); parent != moods[0] {
+			entry.ParentMood = parent
+		}
+	}
+	if len(moods) > 1 {
+		entry.Moods = moods
+	}
+	return entry
+}
 
+func (l *Logger) write(entry any) error {
 	// Преобразуем запись в JSON
 	jsonData, err := json.Marshal(entry)
 	if err != nil {
@@ -0,0 +1,88 @@
+package mood
+
+import "unicode/utf8"
+
+// automaton is an Aho-Corasick matcher that finds every pattern in one pass over the text
+type automaton struct {
+	nodes []acNode
+	// lengths holds the byte length of every pattern
+	lengths []int
+}
+
+type acNode struct {
+	next map[rune]int32
+	fail int32
+	// out lists the patterns that end in this node, including those reachable by fail links
+	out []int32
+}
+
+func newAutomaton(patterns []string) *automaton {
+	a := &automaton{nodes: []acNode{{}}, lengths: make([]int, len(patterns))}
+
+	for id, p := range patterns {
+		a.lengths[id] = len(p)
+		state := int32(0)
+		for _, r := range p {
+			next, ok := a.nodes[state].next[r]
+			if !ok {
+				next = int32(len(a.nodes))
+				a.nodes = append(a.nodes, acNode{})
+				if a.nodes[state].next == nil {
+					a.nodes[state].next = make(map[rune]int32)
+				}
+				a.nodes[state].next[r] = next
+			}
+			state = next
+		}
+		a.nodes[state].out = append(a.nodes[state].out, int32(id))
+	}
+
+	// Ссылки неудач считаем обходом в ширину: у узла глубины n ссылка ведет на узел меньшей глубины
+	queue := make([]int32, 0, len(a.nodes))
+	for _, child := range a.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for r, child := range a.nodes[state].next {
+			fail := a.nodes[state].fail
+			for {
+				if next, ok := a.nodes[fail].next[r]; ok {
+					a.nodes[child].fail = next
+					break
+				}
+				if fail == 0 {
+					break
+				}
+				fail = a.nodes[fail].fail
+			}
+			a.nodes[child].out = append(a.nodes[child].out, a.nodes[a.nodes[child].fail].out...)
+			queue = append(queue, child)
+		}
+	}
+
+	return a
+}
+
+// search calls report with the pattern id and byte offsets of every match
+func (a *automaton) search(text string, report func(id, start, end int)) {
+	state := int32(0)
+	for i, r := range text {
+		for {
+			if next, ok := a.nodes[state].next[r]; ok {
+				state = next
+				break
+			}
+			if state == 0 {
+				break
+			}
+			state = a.nodes[state].fail
+		}
+
+		end := i + utf8.RuneLen(r)
+		for _, id := range a.nodes[state].out {
+			report(int(id), end-a.lengths[id], end)
+		}
+	}
+}
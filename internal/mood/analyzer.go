@@ -0,0 +1,100 @@
+package mood
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Analyzer classifies texts with a lexicon loaded from disk.
+// The lexicon can be swapped at runtime: a failed reload keeps the previous one.
+type Analyzer struct {
+	dir     string
+	lexicon atomic.Pointer[Lexicon]
+}
+
+// NewAnalyzer loads and validates the lexicon files from dir
+func NewAnalyzer(dir string) (*Analyzer, error) {
+	a := &Analyzer{dir: dir}
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Name identifies the analyzer by its lexicon directory
+func (a *Analyzer) Name() string {
+	return "lexicon:" + a.dir
+}
+
+// Analyze classifies the text with the current lexicon
+func (a *Analyzer) Analyze(text string) Result {
+	return a.lexicon.Load().Analyze(text)
+}
+
+// Reload reads the lexicon files again and switches to them if they are valid
+func (a *Analyzer) Reload() error {
+	lex, err := LoadLexicon(a.dir)
+	if err != nil {
+		return err
+	}
+	a.lexicon.Store(lex)
+	log.Printf("Loaded mood lexicon from %s: %v", a.dir, lex.Versions)
+	return nil
+}
+
+// Watch reloads the lexicon on SIGHUP and whenever the files in the directory change.
+// It returns a function that stops watching.
+func (a *Analyzer) Watch(interval time.Duration) (stop func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		defer signal.Stop(hup)
+
+		last := a.fingerprint()
+		for {
+			select {
+			case <-done:
+				return
+			case <-hup:
+				log.Printf("Received SIGHUP, reloading mood lexicon")
+			case <-ticker.C:
+				current := a.fingerprint()
+				if current == last {
+					continue
+				}
+				log.Printf("Mood lexicon files changed, reloading")
+			}
+
+			last = a.fingerprint()
+			if err := a.Reload(); err != nil {
+				log.Printf("Error reloading mood lexicon, keeping the previous one: %v", err)
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// fingerprint summarizes names, sizes and modification times of the lexicon files
+func (a *Analyzer) fingerprint() string {
+	paths, _ := filepath.Glob(filepath.Join(a.dir, "*.json"))
+	var fp string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		fp += fmt.Sprintf("%s:%d:%d;", filepath.Base(path), info.Size(), info.ModTime().UnixNano())
+	}
+	return fp
+}
@@ -0,0 +1,281 @@
+package mood
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+)
+
+// bayesVersion is the format version of the serialized model
+const bayesVersion = 1
+
+// maxTraceTokens limits how many of the most telling words a Bayes result explains
+const maxTraceTokens = 5
+
+// Example is a text labeled with its mood, one line of a training or evaluation corpus
+type Example struct {
+	Text string `json:"text"`
+	Mood string `json:"mood"`
+}
+
+// BayesModel is a multinomial Naive Bayes mood classifier trained offline on
+// labeled examples. It uses the same normalization, stemming and negation as
+// the lexicon, so "не устал" and "устал" are different features.
+type BayesModel struct {
+	Version int `json:"version"`
+	// Alpha is the additive smoothing of word counts
+	Alpha float64 `json:"alpha"`
+	// Docs counts the training examples of every class, Neutral included
+	Docs map[string]int `json:"docs"`
+	// Words counts every feature in the examples of every class
+	Words map[string]map[string]int `json:"words"`
+
+	path string
+	// Посчитанные при загрузке логарифмы вероятностей
+	classes  []string
+	prior    map[string]float64
+	total    map[string]float64
+	vocabLen float64
+}
+
+// TrainBayes counts the features of the examples. Labels must be one of
+// Categories or Neutral.
+func TrainBayes(examples []Example, alpha float64) (*BayesModel, error) {
+	if alpha <= 0 {
+		return nil, fmt.Errorf("alpha must be positive, got %v", alpha)
+	}
+	m := &BayesModel{
+		Version: bayesVersion,
+		Alpha:   alpha,
+		Docs:    make(map[string]int),
+		Words:   make(map[string]map[string]int),
+	}
+	for _, e := range examples {
+		if e.Mood != Neutral && !slices.Contains(Categories, e.Mood) {
+			return nil, fmt.Errorf("unknown mood %q in example %q", e.Mood, e.Text)
+		}
+		m.Docs[e.Mood]++
+		for _, f := range features(tokenizeMarked(e.Text)) {
+			if m.Words[f.name] == nil {
+				m.Words[f.name] = make(map[string]int)
+			}
+			m.Words[f.name][e.Mood]++
+		}
+	}
+	if len(m.Docs) < 2 {
+		return nil, fmt.Errorf("need examples of at least two moods, got %v", m.Docs)
+	}
+	m.prepare()
+	return m, nil
+}
+
+// LoadBayes reads a model saved by Save
+func LoadBayes(path string) (*BayesModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mood model: %v", err)
+	}
+	var m BayesModel
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if m.Version != bayesVersion {
+		return nil, fmt.Errorf("unsupported mood model version %d in %s", m.Version, path)
+	}
+	if m.Alpha <= 0 || len(m.Docs) < 2 {
+		return nil, fmt.Errorf("invalid mood model %s: alpha %v, classes %v", path, m.Alpha, m.Docs)
+	}
+	m.path = path
+	m.prepare()
+	return &m, nil
+}
+
+// Save writes the model as JSON
+func (m *BayesModel) Save(path string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal mood model: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write mood model: %v", err)
+	}
+	m.path = path
+	return nil
+}
+
+// prepare computes the priors and the per-class totals used by Analyze
+func (m *BayesModel) prepare() {
+	m.classes = m.classes[:0]
+	for _, c := range append(slices.Clone(Categories), Neutral) {
+		if m.Docs[c] > 0 {
+			m.classes = append(m.classes, c)
+		}
+	}
+
+	var docs int
+	for _, n := range m.Docs {
+		docs += n
+	}
+	m.prior = make(map[string]float64, len(m.classes))
+	m.total = make(map[string]float64, len(m.classes))
+	for _, c := range m.classes {
+		m.prior[c] = math.Log(float64(m.Docs[c]) / float64(docs))
+	}
+	for _, counts := range m.Words {
+		for c, n := range counts {
+			m.total[c] += float64(n)
+		}
+	}
+	m.vocabLen = float64(len(m.Words))
+}
+
+// Name identifies the model by its file
+func (m *BayesModel) Name() string {
+	return "bayes:" + m.path
+}
+
+// Analyze picks the most probable class. Scores hold the posterior probability
+// of every category, Confidence is the margin between the two most probable
+// classes, and Matches list the words that speak most for the winner.
+func (m *BayesModel) Analyze(text string) Result {
+	tokens := tokenizeMarked(text)
+	feats := features(tokens)
+
+	logp := make(map[string]float64, len(m.classes))
+	for _, c := range m.classes {
+		logp[c] = m.prior[c]
+		for _, f := range feats {
+			logp[c] += m.wordLog(f.name, c)
+		}
+	}
+
+	// Переводим логарифмы в вероятности, вычитая максимум, чтобы не уйти в ноль
+	best := math.Inf(-1)
+	for _, lp := range logp {
+		best = max(best, lp)
+	}
+	var sum float64
+	prob := make(map[string]float64, len(logp))
+	for c, lp := range logp {
+		prob[c] = math.Exp(lp - best)
+		sum += prob[c]
+	}
+	for c := range prob {
+		prob[c] /= sum
+	}
+
+	ranked := slices.Clone(m.classes)
+	sort.SliceStable(ranked, func(i, j int) bool { return prob[ranked[i]] > prob[ranked[j]] })
+
+	scores := make([]Score, len(Categories))
+	for i, c := range Categories {
+		scores[i] = Score{Mood: c, Score: prob[c]}
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+
+	result := Result{Mood: Neutral, Scores: scores}
+	if len(ranked) > 1 {
+		result.Confidence = prob[ranked[0]] - prob[ranked[1]]
+	}
+	winner := ranked[0]
+	if winner == Neutral || result.Confidence < MinConfidence {
+		return result
+	}
+
+	result.Matches, result.Intensity = m.explain(tokens, feats, winner)
+	result.Mood = winner
+	for i := range scores {
+		if scores[i].Mood == winner {
+			scores[i].Intensity = result.Intensity
+		}
+	}
+	result.Moods = []Score{{Mood: winner, Score: prob[winner], Intensity: result.Intensity}}
+	return result
+}
+
+// wordLog is the smoothed log probability of a feature in a class
+func (m *BayesModel) wordLog(word, class string) float64 {
+	n := float64(m.Words[word][class])
+	return math.Log((n + m.Alpha) / (m.total[class] + m.Alpha*m.vocabLen))
+}
+
+// explain lists the known words that favour the winner over the average class
+// the most, and grades the intensity by the modifier in front of the top word
+func (m *BayesModel) explain(tokens []token, feats []feature, winner string) ([]Match, Intensity) {
+	var matches []Match
+	var positions []int
+	for _, f := range feats {
+		if m.Words[f.name] == nil {
+			continue
+		}
+		var others float64
+		for _, c := range m.classes {
+			others += m.wordLog(f.name, c)
+		}
+		weight := m.wordLog(f.name, winner) - others/float64(len(m.classes))
+		if weight <= 0 {
+			continue
+		}
+		matches = append(matches, Match{
+			Token:    tokens[f.pos].text,
+			Entry:    f.name,
+			Category: winner,
+			Weight:   math.Round(weight*100) / 100,
+			Negated:  f.negated,
+		})
+		positions = append(positions, f.pos)
+	}
+
+	order := make([]int, len(matches))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return matches[order[i]].Weight > matches[order[j]].Weight })
+	if len(order) > maxTraceTokens {
+		order = order[:maxTraceTokens]
+	}
+
+	intensity := Moderate
+	top := make([]Match, len(order))
+	for i, idx := range order {
+		top[i] = matches[idx]
+		if i == 0 {
+			intensity = hitIntensity(tokens, positions[idx], matches[idx].Negated)
+		}
+	}
+	return top, intensity
+}
+
+// feature is a stem as seen by the Bayes model: negated stems get a prefix
+type feature struct {
+	name    string
+	pos     int
+	negated bool
+}
+
+// tokenizeMarked tokenizes the text and marks modifiers, like Lexicon.Analyze does
+func tokenizeMarked(text string) []token {
+	tokens := tokenize(text)
+	markModifiers(tokens)
+	return tokens
+}
+
+// features turns tokens into model features. Отрицания и усилители сами по себе
+// признаками не считаются: они меняют соседние слова.
+func features(tokens []token) []feature {
+	var feats []feature
+	for i, t := range tokens {
+		if negators[t.text] || t.modifier != 0 {
+			continue
+		}
+		f := feature{name: t.stem, pos: i, negated: negated(tokens, i)}
+		if f.negated {
+			f.name = "не_" + f.name
+		}
+		feats = append(feats, f)
+	}
+	return feats
+}
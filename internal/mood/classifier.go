@@ -0,0 +1,10 @@
+package mood
+
+// Classifier detects the mood of a text. The lexicon Analyzer is the production
+// classifier; a candidate implements the same interface and can run in shadow
+// mode next to it before it is switched on.
+type Classifier interface {
+	// Name identifies the classifier in logs, e.g. "lexicon:configs/lexicon"
+	Name() string
+	Analyze(text string) Result
+}
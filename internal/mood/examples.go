@@ -0,0 +1,77 @@
+package mood
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadExamples reads a labeled corpus: JSONL with {"text", "mood"} objects, or
+// CSV with "text" and "mood" columns named in the header row
+func LoadExamples(path string) ([]Example, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corpus: %v", err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return readCSV(path, file)
+	}
+	return readJSONL(path, file)
+}
+
+func readJSONL(path string, r io.Reader) ([]Example, error) {
+	var examples []Example
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e Example
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		examples = append(examples, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return examples, nil
+}
+
+func readCSV(path string, r io.Reader) ([]Example, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	textCol, moodCol := -1, -1
+	for i, name := range rows[0] {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "text":
+			textCol = i
+		case "mood":
+			moodCol = i
+		}
+	}
+	if textCol < 0 || moodCol < 0 {
+		return nil, fmt.Errorf("%s: header must name the text and mood columns, got %v", path, rows[0])
+	}
+
+	examples := make([]Example, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		examples = append(examples, Example{Text: row[textCol], Mood: strings.TrimSpace(row[moodCol])})
+	}
+	return examples, nil
+}
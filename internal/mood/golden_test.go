@@ -0,0 +1,45 @@
+package mood
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// TestGolden is the regression gate of cmd/moodeval -baseline: accuracy on the
+// golden corpus must not drop below testdata/golden_baseline.json. After an
+// improvement record the new value with cmd/moodeval -update-baseline.
+func TestGolden(t *testing.T) {
+	lex := loadLexicon(t)
+	examples, err := LoadExamples("testdata/golden.jsonl")
+	if err != nil {
+		t.Fatalf("Error loading examples: %v", err)
+	}
+	if len(examples) == 0 {
+		t.Fatal("testdata/golden.jsonl is empty")
+	}
+
+	data, err := os.ReadFile("testdata/golden_baseline.json")
+	if err != nil {
+		t.Fatalf("Error reading baseline: %v", err)
+	}
+	var baseline struct {
+		Accuracy float64 `json:"accuracy"`
+	}
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		t.Fatalf("Error parsing baseline: %v", err)
+	}
+
+	correct := 0
+	for _, e := range examples {
+		if got := lex.Analyze(e.Text).Mood; got == e.Mood {
+			correct++
+		} else {
+			t.Logf("%q: expected %s, got %s", e.Text, e.Mood, got)
+		}
+	}
+	accuracy := float64(correct) / float64(len(examples))
+	if accuracy+1e-9 < baseline.Accuracy {
+		t.Errorf("Accuracy %.1f%% (%d/%d) is below the baseline %.1f%%", 100*accuracy, correct, len(examples), 100*baseline.Accuracy)
+	}
+}
@@ -0,0 +1,122 @@
+package mood
+
+import "strings"
+
+// Intensity grades how strongly a mood is expressed
+type Intensity int
+
+const (
+	Slight Intensity = iota + 1
+	Moderate
+	Strong
+)
+
+func (i Intensity) String() string {
+	switch i {
+	case Slight:
+		return "slight"
+	case Moderate:
+		return "moderate"
+	case Strong:
+		return "strong"
+	}
+	return "none"
+}
+
+const (
+	// modifierScope is how many tokens before a hit a modifier may stand
+	modifierScope = 2
+	// strongScore makes a mood strong on its own: "устал, вымотан, нет сил"
+	strongScore = 3.0
+)
+
+// modifiers are intensifiers and diminishers with the level they set.
+// Фразы из нескольких слов ("капец как") перечисляются через пробел.
+var modifiers = map[string]Intensity{
+	// Усилители
+	"очень":       Strong,
+	"сильно":      Strong,
+	"совсем":      Strong,
+	"жутко":       Strong,
+	"ужасно":      Strong,
+	"безумно":     Strong,
+	"дико":        Strong,
+	"адски":       Strong,
+	"смертельно":  Strong,
+	"максимально": Strong,
+	"реально":     Strong,
+	"капец":       Strong,
+	"капец как":   Strong,
+	"пипец":       Strong,
+	"пипец как":   Strong,
+	"до смерти":   Strong,
+
+	// Ослабители
+	"немного":   Slight,
+	"немножко":  Slight,
+	"чуть":      Slight,
+	"чуть-чуть": Slight,
+	"чуточку":   Slight,
+	"слегка":    Slight,
+	"капельку":  Slight,
+	"малость":   Slight,
+	"слегонца":  Slight,
+	"отчасти":   Slight,
+	"несильно":  Slight,
+}
+
+// maxModifierWords is the length of the longest modifier phrase
+const maxModifierWords = 2
+
+// markModifiers sets the modifier level on every token that is part of a modifier,
+// preferring the longest phrase
+func markModifiers(tokens []token) {
+	for i := 0; i < len(tokens); i++ {
+		for n := maxModifierWords; n >= 1; n-- {
+			if i+n > len(tokens) || tokens[i+n-1].clause != tokens[i].clause {
+				continue
+			}
+			words := make([]string, n)
+			for j := range words {
+				words[j] = tokens[i+j].text
+			}
+			level, ok := modifiers[strings.Join(words, " ")]
+			if !ok {
+				continue
+			}
+			for j := i; j < i+n; j++ {
+				tokens[j].modifier = level
+			}
+			i += n - 1
+			break
+		}
+	}
+}
+
+// hitIntensity grades a single hit by the modifier and negation in front of it
+func hitIntensity(tokens []token, pos int, isNegated bool) Intensity {
+	level := Moderate
+	modPos := -1
+	for i := pos - 1; i >= 0 && i >= pos-modifierScope; i-- {
+		if tokens[i].clause != tokens[pos].clause {
+			break
+		}
+		if tokens[i].modifier != 0 {
+			level, modPos = tokens[i].modifier, i
+			break
+		}
+	}
+
+	if !isNegated {
+		return level
+	}
+
+	// "не очень хорошо" — усилитель после отрицания смягчает, а не усиливает
+	if modPos > 0 && level == Strong && negators[tokens[modPos-1].text] {
+		return Slight
+	}
+	if level > Slight {
+		level--
+	}
+	return level
+}
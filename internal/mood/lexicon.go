@@ -0,0 +1,173 @@
+package mood
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"tg_bot/internal/stemmer"
+)
+
+// lexiconFile is a single versioned JSON file with the keyword groups of one mood.
+// Служебные слова ("как", "будто" и т.п.) держим с малым весом, чтобы они
+// не решали исход в одиночку. Отрицания и усилители в словарь не входят —
+// они задаются в коде.
+type lexiconFile struct {
+	Version  int     `json:"version"`
+	Category string  `json:"category"`
+	Comment  string  `json:"comment"`
+	Groups   []group `json:"groups"`
+}
+
+// group is a set of stems that share the same weight
+type group struct {
+	Comment string   `json:"comment"`
+	Weight  float64  `json:"weight"`
+	Stems   []string `json:"stems"`
+	// Match is "stem" (default) to compare word stems, or "prefix" to match
+	// any word that starts with the entry, like the old lexicon did
+	Match string `json:"match,omitempty"`
+}
+
+// Match modes of a lexicon group
+const (
+	MatchStem   = "stem"
+	MatchPrefix = "prefix"
+)
+
+// maxWeight guards against typos like 100 instead of 1.0
+const maxWeight = 10
+
+// Lexicon is a validated set of mood keywords ready for matching
+type Lexicon struct {
+	// Versions maps each loaded file name to its version
+	Versions map[string]int
+	stems    []entry
+	phrases  []entry
+
+	// stemIndex maps Snowball stems to entries; the automaton finds all prefix
+	// entries in one pass and refs maps its patterns back
+	stemIndex map[string][]patternRef
+	automaton *automaton
+	// fuzzy groups stem-mode words by their first letter for typo matching
+	fuzzy map[rune][]fuzzyRef
+	refs  [][]patternRef
+}
+
+// LoadLexicon reads and validates every *.json file in dir
+func LoadLexicon(dir string) (*Lexicon, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lexicon files: %v", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no lexicon files found in %s", dir)
+	}
+
+	lex := &Lexicon{Versions: make(map[string]int)}
+	groups := make(map[string][]group)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lexicon file: %v", err)
+		}
+
+		var file lexiconFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		if err := file.validate(); err != nil {
+			return nil, fmt.Errorf("invalid lexicon %s: %v", path, err)
+		}
+
+		lex.Versions[filepath.Base(path)] = file.Version
+		groups[file.Category] = append(groups[file.Category], file.Groups...)
+	}
+
+	lex.stems, lex.phrases = compile(groups)
+	lex.buildIndex()
+	return lex, nil
+}
+
+func (f *lexiconFile) validate() error {
+	if f.Version < 1 {
+		return fmt.Errorf("version must be a positive number, got %d", f.Version)
+	}
+	if !slices.Contains(Categories, f.Category) {
+		return fmt.Errorf("unknown category %q", f.Category)
+	}
+	if len(f.Groups) == 0 {
+		return fmt.Errorf("no groups")
+	}
+
+	for i, g := range f.Groups {
+		if g.Weight <= 0 || g.Weight > maxWeight {
+			return fmt.Errorf("group %d (%s): weight must be in (0, %d], got %v", i, g.Comment, maxWeight, g.Weight)
+		}
+		if len(g.Stems) == 0 {
+			return fmt.Errorf("group %d (%s): no stems", i, g.Comment)
+		}
+		if g.Match != "" && g.Match != MatchStem && g.Match != MatchPrefix {
+			return fmt.Errorf("group %d (%s): unknown match mode %q", i, g.Comment, g.Match)
+		}
+		for _, stem := range g.Stems {
+			if stem == "" || stem != strings.ToLower(strings.TrimSpace(stem)) {
+				return fmt.Errorf("group %d (%s): stem %q must be lower-case and trimmed", i, g.Comment, stem)
+			}
+			if negators[stem] {
+				return fmt.Errorf("group %d (%s): %q is a negator and is handled by the analyzer", i, g.Comment, stem)
+			}
+		}
+	}
+	return nil
+}
+
+// entry is a single deduplicated stem or phrase with its weight
+type entry struct {
+	category string
+	// text is the entry as written in the lexicon file
+	text string
+	// words holds one stem, or every word of a phrase, in the form compared with tokens:
+	// Snowball stems, or the words as written for prefix matching
+	words []string
+	// raw holds the words as written in the file
+	raw    []string
+	prefix bool
+	weight float64
+}
+
+func compile(lex map[string][]group) (stems, phrases []entry) {
+	for _, category := range Categories {
+		seen := make(map[string]bool)
+		for _, g := range lex[category] {
+			prefix := g.Match == MatchPrefix
+			for _, stem := range g.Stems {
+				raw := strings.Fields(stem)
+				words := slices.Clone(raw)
+				if !prefix {
+					for i, w := range words {
+						words[i] = stemmer.Russian(w)
+					}
+				}
+
+				// "устал" и "устала" дают одну основу и считаются одним совпадением
+				key := fmt.Sprintf("%t:%s", prefix, strings.Join(words, " "))
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				e := entry{category: category, text: stem, words: words, raw: raw, prefix: prefix, weight: g.Weight}
+				if len(e.words) > 1 {
+					phrases = append(phrases, e)
+				} else {
+					stems = append(stems, e)
+				}
+			}
+		}
+	}
+	return stems, phrases
+}
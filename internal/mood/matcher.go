@@ -0,0 +1,206 @@
+package mood
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"tg_bot/internal/normalize"
+)
+
+// hit is a lexicon entry found at a token position
+type hit struct {
+	entry
+	pos int
+}
+
+// patternRef links a stem or an automaton pattern back to the lexicon entry.
+// stem is the index of a single-word entry, or -1 for a word of a phrase.
+type patternRef struct {
+	stem   int
+	phrase int
+	word   int
+}
+
+// fuzzyRef is a stem-mode word that a token with a typo may still match
+type fuzzyRef struct {
+	stem string
+	ref  patternRef
+}
+
+// buildIndex prepares the lookups: stem-mode words go to a map keyed by the
+// Snowball stem, prefix-mode words are compiled into a single automaton
+func (l *Lexicon) buildIndex() {
+	l.stemIndex = make(map[string][]patternRef)
+	l.fuzzy = make(map[rune][]fuzzyRef)
+	ids := make(map[string]int)
+	var patterns []string
+	add := func(e entry, word int, ref patternRef) {
+		if !e.prefix {
+			stem, raw := e.words[word], e.raw[word]
+			l.stemIndex[stem] = append(l.stemIndex[stem], ref)
+			// Многие записи в словаре — уже основы ("паршив", "хренов"), и стеммер
+			// обрезает их еще раз. Такая запись совпадает и с основой слова как есть.
+			if raw != stem {
+				l.stemIndex[raw] = append(l.stemIndex[raw], ref)
+			}
+			first, _ := utf8.DecodeRuneInString(stem)
+			l.fuzzy[first] = append(l.fuzzy[first], fuzzyRef{stem: stem, ref: ref})
+			return
+		}
+		// Пробел в начале привязывает совпадение к началу токена
+		pattern := " " + e.words[word]
+		id, ok := ids[pattern]
+		if !ok {
+			id = len(patterns)
+			ids[pattern] = id
+			patterns = append(patterns, pattern)
+			l.refs = append(l.refs, nil)
+		}
+		l.refs[id] = append(l.refs[id], ref)
+	}
+
+	for i, e := range l.stems {
+		add(e, 0, patternRef{stem: i, phrase: -1, word: 0})
+	}
+	for i, e := range l.phrases {
+		for j := range e.words {
+			add(e, j, patternRef{stem: -1, phrase: i, word: j})
+		}
+	}
+
+	l.automaton = newAutomaton(patterns)
+}
+
+// match finds lexicon entries in the tokens: one map lookup per token stem and
+// one automaton pass for prefix entries. A phrase consumes its words, so
+// "нет сил" is not also counted as a bare "сил".
+func (l *Lexicon) match(tokens []token) []hit {
+	found := make([][]patternRef, len(tokens))
+	var text strings.Builder
+	starts := make([]int, len(tokens))
+	for i, t := range tokens {
+		found[i] = append(found[i], l.stemIndex[t.stem]...)
+		text.WriteByte(' ')
+		starts[i] = text.Len()
+		text.WriteString(t.text)
+	}
+
+	l.automaton.search(text.String(), func(id, start, end int) {
+		// Совпадение начинается с пробела перед токеном
+		pos, _ := slices.BinarySearch(starts, start+1)
+		found[pos] = append(found[pos], l.refs[id]...)
+	})
+
+	l.matchFuzzy(tokens, found)
+	return l.collect(tokens, found)
+}
+
+// matchFuzzy looks up stems with typos for the tokens that matched nothing:
+// "усталл" and "вымотна" still count. Only the closest stem is taken, and
+// the first letter must be right, which keeps the scan short. The number of
+// typos follows the shorter of the two stems: "у" is not a typo of "уныло",
+// and "подруг" is not a typo of "сил".
+func (l *Lexicon) matchFuzzy(tokens []token, found [][]patternRef) {
+	for i, t := range tokens {
+		if len(found[i]) > 0 || negators[t.text] || t.modifier != 0 {
+			continue
+		}
+		first, _ := utf8.DecodeRuneInString(t.stem)
+		stemLen := utf8.RuneCountInString(t.stem)
+		if normalize.MaxDistance(stemLen) == 0 {
+			continue
+		}
+		best, bestStem := -1, ""
+		var refs []patternRef
+		for _, f := range l.fuzzy[first] {
+			fLen := utf8.RuneCountInString(f.stem)
+			limit := normalize.MaxDistance(min(stemLen, fLen))
+			if diff := fLen - stemLen; diff > limit || -diff > limit {
+				continue
+			}
+			d := normalize.Distance(t.stem, f.stem)
+			switch {
+			case d > limit:
+			case best < 0 || d < best:
+				best, bestStem, refs = d, f.stem, []patternRef{f.ref}
+			case d == best && f.stem == bestStem:
+				// Одна опечатка не должна засчитываться за два разных слова
+				refs = append(refs, f.ref)
+			}
+		}
+		found[i] = refs
+		tokens[i].fuzzy = len(refs) > 0
+	}
+}
+
+// collect turns the per-token references into hits, resolving phrases first.
+// Of overlapping phrases only the longest counts: "в отличном настроении" is
+// not also "отличное настроение". The same words in several moods all count.
+func (l *Lexicon) collect(tokens []token, found [][]patternRef) []hit {
+	var phrases []hit
+	for i := range tokens {
+		for _, ref := range found[i] {
+			if ref.stem >= 0 || ref.word != 0 || !l.phraseAt(tokens, found, i, ref.phrase) {
+				continue
+			}
+			phrases = append(phrases, hit{entry: l.phrases[ref.phrase], pos: i})
+		}
+	}
+	slices.SortStableFunc(phrases, func(a, b hit) int { return len(b.words) - len(a.words) })
+
+	var hits []hit
+	covered := make([]bool, len(tokens))
+	type span struct{ pos, n int }
+	taken := make(map[span]bool)
+	for _, h := range phrases {
+		s := span{h.pos, len(h.words)}
+		if !taken[s] && slices.Contains(covered[s.pos:s.pos+s.n], true) {
+			continue
+		}
+		taken[s] = true
+		hits = append(hits, h)
+		for j := s.pos; j < s.pos+s.n; j++ {
+			covered[j] = true
+			tokens[j].inPhrase = true
+		}
+	}
+	// Дальше снова в порядке текста
+	slices.SortStableFunc(hits, func(a, b hit) int { return a.pos - b.pos })
+
+	for i, t := range tokens {
+		if covered[i] || negators[t.text] || t.modifier != 0 {
+			continue
+		}
+		// Порядок как в словаре, чтобы суммы весов не зависели от порядка находок
+		var stems []int
+		for _, ref := range found[i] {
+			if ref.stem >= 0 {
+				stems = append(stems, ref.stem)
+			}
+		}
+		slices.Sort(stems)
+		for _, idx := range slices.Compact(stems) {
+			hits = append(hits, hit{entry: l.stems[idx], pos: i})
+		}
+	}
+
+	return hits
+}
+
+// phraseAt reports whether every word of the phrase was found in order from pos on
+func (l *Lexicon) phraseAt(tokens []token, found [][]patternRef, pos, phrase int) bool {
+	words := l.phrases[phrase].words
+	if pos+len(words) > len(tokens) {
+		return false
+	}
+	for j := 1; j < len(words); j++ {
+		if tokens[pos+j].clause != tokens[pos].clause {
+			return false
+		}
+		if !slices.Contains(found[pos+j], patternRef{stem: -1, phrase: phrase, word: j}) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package mood
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// sample is a typical voice transcript; it is repeated to build long inputs
+var sample = []string{
+	"Сегодня я очень устал, с утра на работе завал, голова как ватная.",
+	"Но вечером погулял в парке и стало немного легче, даже настроение хорошее.",
+	"Не могу сказать, что всё плохо, просто хочется выспаться и чтобы никто не трогал.",
+	"Завтра выходной, так что планирую кайфовать и ничего не делать.",
+}
+
+// transcript repeats the sample until it has the requested number of words
+func transcript(words int) string {
+	all := strings.Fields(strings.Join(sample, " "))
+	out := make([]string, 0, words)
+	for len(out) < words {
+		out = append(out, all[len(out)%len(all)])
+	}
+	return strings.Join(out, " ")
+}
+
+// naiveMatch is the matcher from before the automaton, kept as a benchmark
+// baseline: every phrase and every dictionary word is checked against every
+// token with strings.Contains / strings.HasPrefix
+func naiveMatch(l *Lexicon, tokens []token) []hit {
+	var hits []hit
+	covered := make([]bool, len(tokens))
+
+	for i := range tokens {
+		for _, e := range l.phrases {
+			if naiveMatchPhrase(tokens, i, e.raw) {
+				hits = append(hits, hit{entry: e, pos: i})
+				for j := i; j < i+len(e.raw); j++ {
+					covered[j] = true
+				}
+			}
+		}
+	}
+
+	for i, t := range tokens {
+		if covered[i] || negators[t.text] || t.modifier != 0 {
+			continue
+		}
+		for _, e := range l.stems {
+			if strings.Contains(t.text, e.raw[0]) {
+				hits = append(hits, hit{entry: e, pos: i})
+			}
+		}
+	}
+
+	return hits
+}
+
+func naiveMatchPhrase(tokens []token, pos int, words []string) bool {
+	if pos+len(words) > len(tokens) {
+		return false
+	}
+	for j, w := range words {
+		t := tokens[pos+j]
+		if t.clause != tokens[pos].clause || !strings.HasPrefix(t.text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func BenchmarkAnalyze(b *testing.B) {
+	lex := loadLexicon(b)
+	for _, words := range []int{20, 200, 2000} {
+		text := transcript(words)
+		b.Run(fmt.Sprintf("words=%d", words), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				lex.Analyze(text)
+			}
+		})
+	}
+}
+
+// BenchmarkMatch compares the automaton with the old per-word scan
+func BenchmarkMatch(b *testing.B) {
+	lex := loadLexicon(b)
+	for _, words := range []int{20, 200, 2000} {
+		text := transcript(words)
+		for _, m := range []struct {
+			name  string
+			match func([]token) []hit
+		}{
+			{"automaton", lex.match},
+			{"naive", func(tokens []token) []hit { return naiveMatch(lex, tokens) }},
+		} {
+			b.Run(fmt.Sprintf("words=%d/%s", words, m.name), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					// match помечает токены, поэтому каждый раз разбираем текст заново
+					tokens := tokenize(text)
+					markModifiers(tokens)
+					m.match(tokens)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package mood
+
+import (
+	"sort"
+	"strings"
+)
+
+// Mood categories returned by Analyze
+const (
+	Energized = "energized"
+	Tired     = "tired"
+	Positive  = "positive"
+	Negative  = "negative"
+	Neutral   = "neutral"
+
+	// Finer categories; see Parents
+	Anxiety = "anxiety"
+	Anger   = "anger"
+	Sadness = "sadness"
+	Stress  = "stress"
+	Calm    = "calm"
+)
+
+// Categories lists the scored moods. The order breaks ties between equal scores.
+var Categories = []string{Energized, Tired, Positive, Calm, Negative, Anxiety, Anger, Sadness, Stress}
+
+// Parents maps the finer categories to the broad mood they refine.
+// Старые записи в логах с "negative" остаются корректными: это родитель
+// тревоги, злости, грусти и стресса.
+var Parents = map[string]string{
+	Anxiety: Negative,
+	Anger:   Negative,
+	Sadness: Negative,
+	Stress:  Negative,
+	Calm:    Positive,
+}
+
+// Parent returns the broad mood of a category, or the category itself if it has no parent
+func Parent(mood string) string {
+	if parent, ok := Parents[mood]; ok {
+		return parent
+	}
+	return mood
+}
+
+const (
+	// MinScore is the lowest winning score the bot acts on
+	MinScore = 1.0
+	// MinConfidence is the lowest margin between the winner and the runner-up
+	MinConfidence = 0.3
+	// mixedRatio is how close to the winner another mood must score to be reported too
+	mixedRatio = 0.5
+)
+
+// Score is the weighted hit count of a single mood category
+type Score struct {
+	Mood  string
+	Score float64
+	// Intensity is zero when the category has no hits
+	Intensity Intensity
+}
+
+// Result is the outcome of mood analysis
+type Result struct {
+	// Mood is the winning category, or Neutral when the signal is weak or ambiguous
+	Mood string
+	// Scores holds every category ranked from the highest score to the lowest
+	Scores []Score
+	// Confidence is the relative margin between the winner and the strongest
+	// competing signal found in other words, from 0 to 1
+	Confidence float64
+	// Intensity is the level of the winning mood, zero for Neutral
+	Intensity Intensity
+	// Moods holds every detected state starting with the winner: "устал, но доволен"
+	// gives tired and positive. It is empty for Neutral.
+	Moods []Score
+	// Matches explains the scores: every lexicon hit in the order of the text.
+	// The lexicon fills it for Neutral too, so a weak or ambiguous signal can be traced.
+	Matches []Match
+}
+
+// Match is a single lexicon hit that contributed to the scores
+type Match struct {
+	// Token is the matched word, or the words of a phrase, after normalization
+	Token string `json:"token"`
+	// Entry is the stem or phrase as written in the lexicon file
+	Entry    string  `json:"entry"`
+	Category string  `json:"category"`
+	Weight   float64 `json:"weight"`
+	// Negated is set when a negator turned the hit into the opposite category;
+	// Category and Weight are given after the flip
+	Negated bool `json:"negated,omitempty"`
+	// Fuzzy is set when the word matched with a typo
+	Fuzzy bool `json:"fuzzy,omitempty"`
+}
+
+// Mixed reports whether the text expresses more than one mood
+func (r Result) Mixed() bool {
+	return len(r.Moods) > 1
+}
+
+// Analyze scores the text against every mood category and picks the winner
+func (l *Lexicon) Analyze(text string) Result {
+	tokens := tokenize(text)
+	markModifiers(tokens)
+
+	hits := l.match(tokens)
+	// Фразы находятся раньше отдельных слов, а в объяснении нужен порядок текста
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].pos < hits[j].pos
+	})
+	totals := make(map[string]float64, len(Categories))
+	levels := make(map[string]Intensity, len(Categories))
+	matches := make([]Match, len(hits))
+	for i, h := range hits {
+		isNegated := negated(tokens, h.pos)
+		// "не устал" говорит скорее о бодрости, но слабее, чем прямое "бодр"
+		if isNegated {
+			hits[i].category, hits[i].weight = opposite[h.category], h.weight*negationWeight[h.category]
+		}
+		totals[hits[i].category] += hits[i].weight
+		levels[hits[i].category] = max(levels[hits[i].category], hitIntensity(tokens, h.pos, isNegated))
+		matches[i] = traceHit(tokens, hits[i], isNegated)
+	}
+
+	scores := make([]Score, len(Categories))
+	for i, category := range Categories {
+		scores[i] = Score{Mood: category, Score: totals[category], Intensity: levels[category]}
+		if scores[i].Score >= strongScore {
+			scores[i].Intensity = Strong
+		}
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+
+	// Соперником считаем только то, что найдено в других словах: если "хорошо"
+	// есть в двух словарях, это одно слово, а не два разных сигнала
+	result := Result{Mood: Neutral, Scores: scores, Matches: matches}
+	top := scores[0].Score
+	exclusive := exclusiveScores(hits, scores[0].Mood)
+	result.Confidence = confidence(scores, exclusive, scores[0].Mood, "")
+	if top < MinScore {
+		return result
+	}
+
+	result.Moods = []Score{scores[0]}
+	for _, s := range scores[1:] {
+		if exclusive[s.Mood] >= MinScore && exclusive[s.Mood] >= top*mixedRatio {
+			result.Moods = append(result.Moods, s)
+		}
+	}
+
+	// Неуверенный результат отдаем как нейтральный, чтобы бот переспросил
+	if !result.Mixed() && result.Confidence < MinConfidence {
+		result.Moods = nil
+		return result
+	}
+
+	moods := preferChildren(result.Moods)
+	if len(moods) == 1 && result.Mixed() {
+		// Родитель и уточнение схлопнулись в одно настроение: уверенность считаем
+		// заново, без родителя, и неуверенный результат снова нейтральный
+		winner := moods[0].Mood
+		result.Confidence = confidence(scores, exclusiveScores(hits, winner), winner, Parents[winner])
+		if result.Confidence < MinConfidence {
+			result.Moods = nil
+			return result
+		}
+	}
+	result.Moods = moods
+	result.Mood = result.Moods[0].Mood
+	result.Intensity = result.Moods[0].Intensity
+	return result
+}
+
+// confidence is how far the winner leads the strongest rival, counting only
+// what the rival found in words the winner does not claim. The ignored mood,
+// e.g. the parent the winner refines, is not a rival.
+func confidence(scores []Score, exclusive map[string]float64, winner, ignored string) float64 {
+	var top, second float64
+	for _, s := range scores {
+		switch s.Mood {
+		case winner:
+			top = s.Score
+		case ignored:
+		default:
+			second = max(second, exclusive[s.Mood])
+		}
+	}
+	if top == 0 {
+		return 0
+	}
+	return (top - second) / top
+}
+
+// preferChildren drops a broad mood when one of its finer categories is detected too:
+// "хреново и тревожно" — это тревога, а не тревога и негатив
+func preferChildren(moods []Score) []Score {
+	refined := make(map[string]bool)
+	for _, m := range moods {
+		if parent, ok := Parents[m.Mood]; ok {
+			refined[parent] = true
+		}
+	}
+
+	kept := moods[:0:0]
+	for _, m := range moods {
+		if !refined[m.Mood] {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// traceHit describes a hit for Result.Matches
+func traceHit(tokens []token, h hit, isNegated bool) Match {
+	words := make([]string, len(h.words))
+	var fuzzy bool
+	for i := range h.words {
+		words[i] = tokens[h.pos+i].text
+		fuzzy = fuzzy || tokens[h.pos+i].fuzzy
+	}
+	return Match{
+		Token:    strings.Join(words, " "),
+		Entry:    h.text,
+		Category: h.category,
+		Weight:   h.weight,
+		Negated:  isNegated,
+		Fuzzy:    fuzzy,
+	}
+}
+
+// exclusiveScores sums hits on tokens that the winning mood does not claim.
+// Слово "хорошо" есть и в positive, и в energized — это одно состояние, а не два.
+func exclusiveScores(hits []hit, winner string) map[string]float64 {
+	claimed := make(map[int]bool)
+	for _, h := range hits {
+		if h.category == winner {
+			claimed[h.pos] = true
+		}
+	}
+
+	exclusive := make(map[string]float64)
+	for _, h := range hits {
+		if !claimed[h.pos] {
+			exclusive[h.category] += h.weight
+		}
+	}
+	return exclusive
+}
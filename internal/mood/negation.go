@@ -0,0 +1,57 @@
+package mood
+
+// negationScope is how many tokens after a negator are affected by it
+const negationScope = 3
+
+// negationWeight scales a flipped hit by its original category.
+// Отрицание хорошего звучит так же ясно, как плохое ("не хорошо"),
+// а отрицание плохого слабее хорошего: "не устал" еще не значит "бодр".
+var negationWeight = map[string]float64{
+	Positive:  1,
+	Energized: 1,
+	Negative:  0.5,
+	Tired:     0.5,
+	Calm:      1,
+	Anxiety:   0.5,
+	Anger:     0.5,
+	Sadness:   0.5,
+	Stress:    0.5,
+}
+
+// negators flip the polarity of the next few tokens in the same clause
+var negators = map[string]bool{
+	"не":  true,
+	"ни":  true,
+	"нет": true,
+	"без": true,
+	// "ничего хорошего", "ничего не радует"
+	"ничего": true,
+}
+
+// opposite maps every mood to the one a negation turns it into
+var opposite = map[string]string{
+	Positive:  Negative,
+	Negative:  Positive,
+	Tired:     Energized,
+	Energized: Tired,
+	// "не спокойно" — тревога, а "не злюсь" и "не тревожно" — скорее спокойствие
+	Calm:    Anxiety,
+	Anxiety: Calm,
+	Anger:   Calm,
+	Stress:  Calm,
+	Sadness: Positive,
+}
+
+// negated reports whether a negator precedes the token at pos within the same clause
+func negated(tokens []token, pos int) bool {
+	for i := pos - 1; i >= 0 && i >= pos-negationScope; i-- {
+		if tokens[i].clause != tokens[pos].clause {
+			return false
+		}
+		// "нет" из фразы "нет сил" уже засчитано и ничего не отрицает
+		if negators[tokens[i].text] && !tokens[i].inPhrase {
+			return true
+		}
+	}
+	return false
+}
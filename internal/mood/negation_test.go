@@ -0,0 +1,29 @@
+package mood
+
+import "testing"
+
+func loadLexicon(t testing.TB) *Lexicon {
+	t.Helper()
+	lex, err := LoadLexicon("../../configs/lexicon")
+	if err != nil {
+		t.Fatalf("Error loading lexicon: %v", err)
+	}
+	return lex
+}
+
+func TestNegation(t *testing.T) {
+	lex := loadLexicon(t)
+	examples, err := LoadExamples("testdata/negation.jsonl")
+	if err != nil {
+		t.Fatalf("Error loading examples: %v", err)
+	}
+	if len(examples) == 0 {
+		t.Fatal("testdata/negation.jsonl is empty")
+	}
+
+	for _, e := range examples {
+		if got := lex.Analyze(e.Text).Mood; got != e.Mood {
+			t.Errorf("Analyze(%q) = %s, want %s", e.Text, got, e.Mood)
+		}
+	}
+}
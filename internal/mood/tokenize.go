@@ -0,0 +1,80 @@
+package mood
+
+import (
+	"strings"
+	"unicode"
+
+	"tg_bot/internal/normalize"
+	"tg_bot/internal/stemmer"
+)
+
+// token is a lower-cased word together with the clause it belongs to
+type token struct {
+	text   string
+	clause int
+	// stem is the Snowball stem of the word
+	stem string
+	// modifier is set when the token is an intensifier or a diminisher
+	modifier Intensity
+	// fuzzy is set when the token matched the lexicon only with a typo
+	fuzzy bool
+	// inPhrase is set when the token is a word of a matched phrase like "нет сил"
+	inPhrase bool
+}
+
+// clauseBreakers are conjunctions that start a new clause, like punctuation does.
+// "устал, но доволен" — отрицание и усилители не переходят через "но".
+var clauseBreakers = map[string]bool{
+	"но":     true,
+	"а":      true,
+	"однако": true,
+	"зато":   true,
+}
+
+// tokenize normalizes the text, splits it into words and emoji and numbers the
+// clauses between punctuation
+func tokenize(text string) []token {
+	var tokens []token
+	var word strings.Builder
+	clause := 0
+	runes := []rune(normalize.Text(text))
+
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := word.String()
+		word.Reset()
+		if clauseBreakers[w] {
+			clause++
+			return
+		}
+		tokens = append(tokens, token{text: w, clause: clause, stem: stemmer.Russian(w)})
+	}
+
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		case r == '-' && word.Len() > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i+1]):
+			// Дефис внутри слова: "чуть-чуть", "как-то"
+			word.WriteRune(r)
+		case unicode.IsSpace(r):
+			flush()
+		case unicode.Is(unicode.So, r):
+			// Каждый эмодзи — отдельное слово: "😴😴" считается дважды, а
+			// модификаторы цвета кожи и вариантов начертания просто отбрасываются
+			flush()
+			word.WriteRune(r)
+			flush()
+		default:
+			flush()
+			if unicode.IsPunct(r) {
+				clause++
+			}
+		}
+	}
+	flush()
+
+	return tokens
+}
@@ -0,0 +1,235 @@
+// Package normalize cleans up hastily typed text before mood classification:
+// stretched letters, Latin look-alikes inside Russian words and translit.
+package normalize
+
+import (
+	"strings"
+	"unicode"
+)
+
+// homoglyphs maps Latin letters to the Cyrillic ones they look like.
+// Заглавные проверяем до перевода в нижний регистр: "B" похожа на "В", а "b" — нет.
+var homoglyphs = map[rune]rune{
+	'A': 'а', 'B': 'в', 'C': 'с', 'E': 'е', 'H': 'н', 'K': 'к', 'M': 'м', 'O': 'о', 'P': 'р', 'T': 'т', 'X': 'х', 'Y': 'у',
+	'a': 'а', 'c': 'с', 'e': 'е', 'k': 'к', 'o': 'о', 'p': 'р', 'x': 'х', 'y': 'у',
+}
+
+// translitDigraphs are checked before single letters, longest first
+var translitDigraphs = []struct {
+	latin    string
+	cyrillic string
+}{
+	{"shch", "щ"}, {"sch", "щ"},
+	{"zh", "ж"}, {"kh", "х"}, {"ch", "ч"}, {"sh", "ш"}, {"ts", "ц"},
+	{"yu", "ю"}, {"ya", "я"}, {"yo", "ё"}, {"ye", "е"},
+	{"ju", "ю"}, {"ja", "я"}, {"jo", "ё"},
+}
+
+var translitLetters = map[rune]string{
+	'a': "а", 'b': "б", 'c': "ц", 'd': "д", 'e': "е", 'f': "ф", 'g': "г", 'h': "х", 'i': "и",
+	'j': "й", 'k': "к", 'l': "л", 'm': "м", 'n': "н", 'o': "о", 'p': "п", 'q': "к", 'r': "р",
+	's': "с", 't': "т", 'u': "у", 'v': "в", 'w': "в", 'x': "кс", 'z': "з",
+}
+
+// maxRepeat is the longest run of one letter kept as is: "сонный" keeps its "нн",
+// "устааал" and "оооочень" are stretched and collapse to a single letter
+const maxRepeat = 2
+
+// Text lower-cases the text and rewrites every word in Cyrillic:
+// mixed-script words get their Latin look-alikes replaced, all-Latin words are
+// read as translit, and letters repeated more than twice collapse to one.
+// Everything except letters is left untouched.
+func Text(text string) string {
+	var out strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) {
+			out.WriteRune(runes[i])
+			i++
+			continue
+		}
+		start := i
+		for i < len(runes) && unicode.IsLetter(runes[i]) {
+			i++
+		}
+		out.WriteString(Word(string(runes[start:i])))
+	}
+	return out.String()
+}
+
+// Word normalizes a single word, see Text
+func Word(word string) string {
+	var hasLatin, hasCyrillic bool
+	for _, r := range word {
+		switch {
+		case isLatin(r):
+			hasLatin = true
+		case unicode.Is(unicode.Cyrillic, r):
+			hasCyrillic = true
+		}
+	}
+
+	switch {
+	case hasLatin && hasCyrillic:
+		word = replaceHomoglyphs(word)
+	case hasLatin:
+		word = translit(strings.ToLower(word))
+	}
+	return collapseRepeats(strings.ToLower(word))
+}
+
+func isLatin(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// replaceHomoglyphs swaps Latin look-alikes for Cyrillic letters; Latin letters
+// that look like nothing Cyrillic are read as translit
+func replaceHomoglyphs(word string) string {
+	var out strings.Builder
+	for _, r := range word {
+		if c, ok := homoglyphs[r]; ok {
+			out.WriteRune(c)
+		} else if isLatin(r) {
+			out.WriteString(translitLetters[unicode.ToLower(r)])
+		} else {
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
+
+// translit converts a lower-case Latin word to Cyrillic: "privet" → "привет"
+func translit(word string) string {
+	var out strings.Builder
+	for i := 0; i < len(word); {
+		rest := word[i:]
+
+		matched := false
+		for _, d := range translitDigraphs {
+			if strings.HasPrefix(rest, d.latin) {
+				out.WriteString(d.cyrillic)
+				i += len(d.latin)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		r := rune(word[i])
+		switch {
+		case r == 'y':
+			// "y" после гласной — это "й" ("kayf", "moy"), иначе "ы" ("ty", "vy")
+			if i > 0 && strings.ContainsRune("aeiou", rune(word[i-1])) {
+				out.WriteString("й")
+			} else {
+				out.WriteString("ы")
+			}
+		case isLatin(r):
+			out.WriteString(translitLetters[r])
+		default:
+			// Буквы других алфавитов оставляем как есть
+			out.WriteString(word[i:])
+			return out.String()
+		}
+		i++
+	}
+	return out.String()
+}
+
+// collapseRepeats shortens runs of more than maxRepeat equal letters to one letter
+func collapseRepeats(word string) string {
+	runes := []rune(word)
+	out := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && runes[j] == runes[i] {
+			j++
+		}
+		if j-i > maxRepeat {
+			out = append(out, runes[i])
+		} else {
+			out = append(out, runes[i:j]...)
+		}
+		i = j
+	}
+	return string(out)
+}
+
+// Distance returns the Damerau-Levenshtein distance (optimal string alignment)
+// between two words in runes: a swap of neighbouring letters counts as one edit
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// Три строки таблицы: перестановка смотрит на две строки назад
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// MaxDistance is the number of typos tolerated in a word of the given length in runes:
+// none in short words, where one letter turns "сил" into "сыл" or "мил"
+func MaxDistance(length int) int {
+	switch {
+	case length < 5:
+		return 0
+	case length < 9:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// ContainsWord reports whether a word of the normalized text starts with the
+// given one, or is the word itself with up to MaxDistance typos: "приветик"
+// and "приивет" contain "привет", "привела" and "привезли" do not
+func ContainsWord(text, word string) bool {
+	limit := MaxDistance(len([]rune(word)))
+	for _, w := range words(text) {
+		// Опечатки допускаем только в слове целиком: начало "приве" есть и у "привела"
+		if strings.HasPrefix(w, word) || Distance(w, word) <= limit {
+			return true
+		}
+	}
+	return false
+}
+
+// HasWord reports whether the normalized text has the word itself, allowing
+// MaxDistance typos: "потом" and "патом" but not "потому"
+func HasWord(text, word string) bool {
+	limit := MaxDistance(len([]rune(word)))
+	for _, w := range words(text) {
+		if w == word {
+			return true
+		}
+		// Лишнее окончание — уже другое слово, а не опечатка: "потому" не "потом"
+		if !strings.HasPrefix(w, word) && Distance(w, word) <= limit {
+			return true
+		}
+	}
+	return false
+}
+
+// words splits the normalized text into words
+func words(text string) []string {
+	return strings.FieldsFunc(Text(text), func(r rune) bool { return !unicode.IsLetter(r) })
+}
@@ -0,0 +1,40 @@
+package normalize
+
+import "testing"
+
+func TestContainsWord(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"привет", true},
+		{"Приветик!", true},
+		{"ну приивет", true},
+		{"privet", true},
+		{"я привела кота", false},
+		{"привезли продукты", false},
+		{"приветствую", true},
+	}
+	for _, tt := range tests {
+		if got := ContainsWord(tt.text, "привет"); got != tt.want {
+			t.Errorf("ContainsWord(%q, \"привет\") = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestHasWord(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"давай потом", true},
+		{"патом", true},
+		{"потому что", false},
+		{"смотрю в потолок", false},
+	}
+	for _, tt := range tests {
+		if got := HasWord(tt.text, "потом"); got != tt.want {
+			t.Errorf("HasWord(%q, \"потом\") = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
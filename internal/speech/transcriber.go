@@ -0,0 +1,27 @@
+package speech
+
+import (
+	"fmt"
+	"log"
+)
+
+// Transcriber turns a downloaded voice message (OGG) into text
+type Transcriber interface {
+	Transcribe(oggPath string) (string, error)
+}
+
+// Transcribe converts the voice message to WAV with ffmpeg and sends it to Deepgram
+func (d *DeepgramClient) Transcribe(oggPath string) (string, error) {
+	wavPath, err := ConvertOggToWav(oggPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert audio: %v", err)
+	}
+	defer CleanupAudioFiles(wavPath)
+	log.Printf("Converted to WAV: %s", wavPath)
+
+	text, err := d.TranscribeAudio(wavPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to transcribe audio: %v", err)
+	}
+	return text, nil
+}
@@ -0,0 +1,189 @@
+// Package stemmer implements the Snowball stemming algorithm for Russian.
+// See https://snowballstem.org/algorithms/russian/stemmer.html
+package stemmer
+
+import "strings"
+
+// suffixGroup is a set of endings removed only when the condition holds
+type suffixGroup struct {
+	suffixes []string
+	// afterAYa requires the ending to follow "а" or "я", which are kept
+	afterAYa bool
+	// runes holds the suffixes split into runes, filled in by init
+	runes [][]rune
+}
+
+var (
+	perfectiveGerund = []suffixGroup{
+		{suffixes: []string{"в", "вши", "вшись"}, afterAYa: true},
+		{suffixes: []string{"ив", "ивши", "ившись", "ыв", "ывши", "ывшись"}},
+	}
+	adjective = []suffixGroup{
+		{suffixes: []string{
+			"ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом",
+			"его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею",
+		}},
+	}
+	participle = []suffixGroup{
+		{suffixes: []string{"ем", "нн", "вш", "ющ", "щ"}, afterAYa: true},
+		{suffixes: []string{"ивш", "ывш", "ующ"}},
+	}
+	reflexive = []suffixGroup{
+		{suffixes: []string{"ся", "сь"}},
+	}
+	verb = []suffixGroup{
+		{suffixes: []string{
+			"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно",
+		}, afterAYa: true},
+		{suffixes: []string{
+			"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым", "ен",
+			"ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю",
+		}},
+	}
+	noun = []suffixGroup{
+		{suffixes: []string{
+			"а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой", "ий", "й",
+			"иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь", "ию", "ью", "ю", "ия",
+			"ья", "я",
+		}},
+	}
+	derivational = []suffixGroup{
+		{suffixes: []string{"ост", "ость"}},
+	}
+	superlative = []suffixGroup{
+		{suffixes: []string{"ейш", "ейше"}},
+	}
+)
+
+func init() {
+	for _, groups := range [][]suffixGroup{
+		perfectiveGerund, adjective, participle, reflexive, verb, noun, derivational, superlative,
+	} {
+		for i := range groups {
+			for _, s := range groups[i].suffixes {
+				groups[i].runes = append(groups[i].runes, []rune(s))
+			}
+		}
+	}
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+// Russian returns the stem of a lower-case Russian word
+func Russian(word string) string {
+	w := []rune(strings.ReplaceAll(word, "ё", "е"))
+	rv, r2 := regions(w)
+
+	// Шаг 1: деепричастия, иначе возвратность и окончания прилагательных, глаголов, существительных
+	if n := longest(w, rv, perfectiveGerund); n > 0 {
+		w = w[:len(w)-n]
+	} else {
+		if n := longest(w, rv, reflexive); n > 0 {
+			w = w[:len(w)-n]
+		}
+		if n := longest(w, rv, adjective); n > 0 {
+			w = w[:len(w)-n]
+			if n := longest(w, rv, participle); n > 0 {
+				w = w[:len(w)-n]
+			}
+		} else if n := longest(w, rv, verb); n > 0 {
+			w = w[:len(w)-n]
+		} else if n := longest(w, rv, noun); n > 0 {
+			w = w[:len(w)-n]
+		}
+	}
+
+	// Шаг 2
+	if hasSuffix(w, rv, "и") {
+		w = w[:len(w)-1]
+	}
+
+	// Шаг 3: словообразовательные суффиксы в R2
+	if n := longest(w, r2, derivational); n > 0 {
+		w = w[:len(w)-n]
+	}
+
+	// Шаг 4: превосходная степень, двойное "н" и мягкий знак
+	if n := longest(w, rv, superlative); n > 0 {
+		w = w[:len(w)-n]
+		if hasSuffix(w, rv, "нн") {
+			w = w[:len(w)-1]
+		}
+	} else if hasSuffix(w, rv, "нн") || hasSuffix(w, rv, "ь") {
+		w = w[:len(w)-1]
+	}
+
+	return string(w)
+}
+
+// regions returns the start of RV and R2.
+// RV follows the first vowel; R1 follows the first consonant after a vowel, R2 is the same inside R1.
+func regions(w []rune) (rv, r2 int) {
+	rv = len(w)
+	for i, r := range w {
+		if isVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+
+	after := func(start int) int {
+		for i := start + 1; i < len(w); i++ {
+			if !isVowel(w[i]) && isVowel(w[i-1]) {
+				return i + 1
+			}
+		}
+		return len(w)
+	}
+	r1 := after(0)
+	if r1 < len(w) {
+		r2 = after(r1)
+	} else {
+		r2 = len(w)
+	}
+	return rv, r2
+}
+
+// hasSuffix reports whether w ends with the suffix lying entirely at or after limit
+func hasSuffix(w []rune, limit int, suffix string) bool {
+	return endsWith(w, limit, []rune(suffix))
+}
+
+func endsWith(w []rune, limit int, suffix []rune) bool {
+	start := len(w) - len(suffix)
+	if start < limit || start < 0 {
+		return false
+	}
+	for i, r := range suffix {
+		if w[start+i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// longest finds the longest matching ending among all groups and returns how many
+// runes to remove, or 0 if it is absent or its condition fails
+func longest(w []rune, limit int, groups []suffixGroup) int {
+	best, bestGroup := 0, -1
+	for gi, g := range groups {
+		for _, s := range g.runes {
+			if len(s) > best && endsWith(w, limit, s) {
+				best, bestGroup = len(s), gi
+			}
+		}
+	}
+	if bestGroup < 0 {
+		return 0
+	}
+
+	if groups[bestGroup].afterAYa {
+		i := len(w) - best - 1
+		if i < limit || (w[i] != 'а' && w[i] != 'я') {
+			return 0
+		}
+	}
+	return best
+}